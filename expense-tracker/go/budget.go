@@ -0,0 +1,413 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Budgets ---
+
+type Period string
+
+const (
+	PeriodWeekly  Period = "weekly"
+	PeriodMonthly Period = "monthly"
+	PeriodYearly  Period = "yearly"
+)
+
+var periods = []Period{PeriodWeekly, PeriodMonthly, PeriodYearly}
+
+func parsePeriod(value string) (Period, error) {
+	period := Period(strings.ToLower(value))
+	if !slices.Contains(periods, period) {
+		return "", fmt.Errorf("periode %q tidak valid (gunakan weekly, monthly atau yearly)", value)
+	}
+	return period, nil
+}
+
+func (p Period) label() string {
+	switch p {
+	case PeriodWeekly:
+		return "mingguan"
+	case PeriodYearly:
+		return "tahunan"
+	}
+	return "bulanan"
+}
+
+// bounds mengembalikan awal periode yang memuat t dan awal periode
+// berikutnya. Minggu dimulai hari Senin.
+func (p Period) bounds(t time.Time) (time.Time, time.Time) {
+	year, month, day := t.Date()
+	switch p {
+	case PeriodWeekly:
+		start := time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 0, 7)
+	case PeriodYearly:
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(1, 0, 0)
+	}
+	start := time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Budget membatasi pengeluaran satu kategori, atau semua kategori bila
+// Category kosong, per periode. Dengan Rollover, sisa anggaran yang tidak
+// terpakai ditambahkan ke periode berikutnya, dihitung sejak periode yang
+// memuat Since; kelebihan belanja tidak mengurangi periode berikutnya.
+type Budget struct {
+	Category string    `json:"category,omitempty"`
+	Period   Period    `json:"period"`
+	Amount   Money     `json:"amount"` // Dalam mata uang dasar
+	Rollover bool      `json:"rollover,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+func (b Budget) name() string {
+	if b.Category == "" {
+		return "anggaran " + b.Period.label()
+	}
+	return "anggaran " + b.Period.label() + " kategori " + b.Category
+}
+
+func (b Budget) covers(e Expense) bool {
+	return b.Category == "" || strings.EqualFold(b.Category, e.Category)
+}
+
+// defaultThresholds adalah persentase pemakaian yang memicu peringatan
+// bila alert_thresholds tidak diatur.
+var defaultThresholds = []int{80, 100}
+
+func (config Config) thresholds() []int {
+	if len(config.Thresholds) == 0 {
+		return defaultThresholds
+	}
+	return config.Thresholds
+}
+
+// budgetUsage adalah pemakaian satu anggaran dalam satu periode.
+type budgetUsage struct {
+	Budget     Budget
+	Start, End time.Time
+	Carry      Money // Sisa periode sebelumnya, bila Rollover
+	Limit      Money // Amount ditambah Carry
+	Spent      Money
+}
+
+func (u budgetUsage) percent() int {
+	if u.Limit.Minor <= 0 {
+		if u.Spent.Minor > 0 {
+			return 999
+		}
+		return 0
+	}
+	return int(u.Spent.Minor * 100 / u.Limit.Minor)
+}
+
+func (u budgetUsage) remaining() Money {
+	return Money{Minor: u.Limit.Minor - u.Spent.Minor, Currency: u.Limit.Currency}
+}
+
+// spent menjumlahkan pengeluaran yang dicakup anggaran dari start sampai
+// sebelum end, dalam mata uang dasar.
+func (config Config) spent(b Budget, expenses []Expense, start, end time.Time) (Money, error) {
+	var matched []Expense
+	for _, e := range expenses {
+		if b.covers(e) && !e.Date.Before(start) && e.Date.Before(end) {
+			matched = append(matched, e)
+		}
+	}
+	return config.totalInBase(matched)
+}
+
+// usage menghitung pemakaian anggaran b pada periode yang memuat at.
+func (config Config) usage(b Budget, expenses []Expense, at time.Time) (budgetUsage, error) {
+	u := budgetUsage{Budget: b, Carry: Money{Currency: b.Amount.Currency}}
+	u.Start, u.End = b.Period.bounds(at)
+
+	if b.Rollover {
+		start, _ := b.Period.bounds(b.Since)
+		for start.Before(u.Start) {
+			_, end := b.Period.bounds(start)
+			spent, err := config.spent(b, expenses, start, end)
+			if err != nil {
+				return budgetUsage{}, err
+			}
+			u.Carry.Minor = max(0, b.Amount.Minor+u.Carry.Minor-spent.Minor)
+			start = end
+		}
+	}
+
+	spent, err := config.spent(b, expenses, u.Start, u.End)
+	if err != nil {
+		return budgetUsage{}, err
+	}
+	u.Spent = spent
+	u.Limit = Money{Minor: b.Amount.Minor + u.Carry.Minor, Currency: b.Amount.Currency}
+	return u, nil
+}
+
+// reachedThreshold mengembalikan ambang tertinggi yang sudah dicapai
+// pemakaian, atau 0 bila belum ada.
+func (config Config) reachedThreshold(u budgetUsage) int {
+	reached := 0
+	for _, threshold := range config.thresholds() {
+		if u.Spent.Minor > 0 && u.percent() >= threshold && threshold > reached {
+			reached = threshold
+		}
+	}
+	return reached
+}
+
+func (config Config) alertMessage(u budgetUsage, threshold int) string {
+	if u.Spent.Minor > u.Limit.Minor {
+		return fmt.Sprintf("⚠️ PERINGATAN: Anda telah melebihi %s sebesar %s! (Terpakai: %s, %d%%)", u.Budget.name(), u.Limit, u.Spent, u.percent())
+	}
+	return fmt.Sprintf("⚠️ PERINGATAN: %s sudah terpakai %d%% (ambang %d%%): %s dari %s, sisa %s", u.Budget.name(), u.percent(), threshold, u.Spent, u.Limit, u.remaining())
+}
+
+// warnBudgets memberi peringatan untuk setiap anggaran yang pemakaiannya
+// naik karena perubahan dari before ke config dan kini mencapai ambang,
+// pada periode yang memuat date.
+func warnBudgets(before []Expense, config Config, date time.Time) {
+	for _, b := range config.Budgets {
+		old, err := config.usage(b, before, date)
+		if err != nil {
+			fmt.Printf("⚠️ %s tidak dapat diperiksa: %v\n", b.name(), err)
+			continue
+		}
+		now, err := config.usage(b, config.Expenses, date)
+		if err != nil {
+			fmt.Printf("⚠️ %s tidak dapat diperiksa: %v\n", b.name(), err)
+			continue
+		}
+		if now.Spent.Minor <= old.Spent.Minor {
+			continue
+		}
+		if threshold := config.reachedThreshold(now); threshold > 0 {
+			fmt.Println(config.alertMessage(now, threshold))
+		}
+	}
+}
+
+// checkBudgets memeriksa semua anggaran pada periode berjalan dan
+// menampilkan yang sudah mencapai ambang peringatan.
+func checkBudgets() {
+	config := loadData()
+	if len(config.Budgets) == 0 {
+		fmt.Println("Belum ada anggaran yang diatur.")
+		return
+	}
+
+	alerts := 0
+	for _, b := range config.Budgets {
+		u, err := config.usage(b, config.Expenses, time.Now())
+		if err != nil {
+			fmt.Printf("⚠️ %s tidak dapat diperiksa: %v\n", b.name(), err)
+			alerts++
+			continue
+		}
+		if threshold := config.reachedThreshold(u); threshold > 0 {
+			fmt.Println(config.alertMessage(u, threshold))
+			alerts++
+		}
+	}
+	if alerts == 0 {
+		fmt.Println("Semua anggaran masih di bawah ambang peringatan.")
+	}
+}
+
+// budgetBarWidth adalah panjang batang pemakaian pada budget status.
+const budgetBarWidth = 20
+
+func budgetBar(percent int) string {
+	filled := min(percent*budgetBarWidth/100, budgetBarWidth)
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", budgetBarWidth-filled) + "]"
+}
+
+func showBudgetStatus() {
+	config := loadData()
+	if len(config.Budgets) == 0 {
+		fmt.Println("Belum ada anggaran yang diatur.")
+		return
+	}
+
+	fmt.Printf("%-14s %-9s %-25s %-14s %-14s %-14s %s\n", "Kategori", "Periode", "Rentang", "Terpakai", "Anggaran", "Sisa", "Pemakaian")
+	fmt.Println(strings.Repeat("-", 124))
+	carried := false
+	for _, b := range config.Budgets {
+		category := b.Category
+		if category == "" {
+			category = "(semua)"
+		}
+		u, err := config.usage(b, config.Expenses, time.Now())
+		if err != nil {
+			fmt.Printf("%-14s %-9s Error: %v\n", category, b.Period.label(), err)
+			continue
+		}
+		limit := u.Limit.String()
+		if u.Carry.Minor > 0 {
+			limit += "*"
+			carried = true
+		}
+		mark := ""
+		if config.reachedThreshold(u) > 0 {
+			mark = " ⚠️"
+		}
+		span := u.Start.Format("2006-01-02") + " s/d " + u.End.AddDate(0, 0, -1).Format("2006-01-02")
+		fmt.Printf("%-14s %-9s %-25s %-14s %-14s %-14s %s %3d%%%s\n",
+			category, b.Period.label(), span, u.Spent, limit, u.remaining(), budgetBar(u.percent()), u.percent(), mark)
+	}
+	if carried {
+		fmt.Println("* termasuk sisa anggaran periode sebelumnya (rollover)")
+	}
+}
+
+// setBudget menambah atau mengganti anggaran untuk kategori dan periode
+// itu. Anggaran yang diganti tetap menghitung rollover dari awalnya.
+func setBudget(config *Config, b Budget) {
+	for i, existing := range config.Budgets {
+		if strings.EqualFold(existing.Category, b.Category) && existing.Period == b.Period {
+			b.Since = existing.Since
+			config.Budgets[i] = b
+			return
+		}
+	}
+	config.Budgets = append(config.Budgets, b)
+	sort.SliceStable(config.Budgets, func(i, j int) bool {
+		a, b := config.Budgets[i], config.Budgets[j]
+		if !strings.EqualFold(a.Category, b.Category) {
+			return strings.ToLower(a.Category) < strings.ToLower(b.Category)
+		}
+		return slices.Index(periods, a.Period) < slices.Index(periods, b.Period)
+	})
+}
+
+func removeBudget(config *Config, category string, period Period) bool {
+	for i, existing := range config.Budgets {
+		if strings.EqualFold(existing.Category, category) && existing.Period == period {
+			config.Budgets = append(config.Budgets[:i], config.Budgets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func parseThresholds(value string) ([]int, error) {
+	var thresholds []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(item), "%"))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("ambang %q tidak valid (gunakan persentase seperti 80,100)", item)
+		}
+		thresholds = append(thresholds, n)
+	}
+	sort.Ints(thresholds)
+	return slices.Compact(thresholds), nil
+}
+
+// runBudgetCommand menangani budget set, remove, status dan alerts. Bentuk
+// lama budget --amount tetap mengatur anggaran bulanan semua kategori.
+func runBudgetCommand(args []string) {
+	sub := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	switch sub {
+	case "", "set":
+		setCmd := flag.NewFlagSet("budget set", flag.ExitOnError)
+		amount := setCmd.String("amount", "", "Jumlah anggaran, dalam mata uang dasar")
+		category := setCmd.String("category", "", "Kategori (kosong: semua kategori)")
+		periodValue := setCmd.String("period", "monthly", "Periode: weekly, monthly atau yearly")
+		rollover := setCmd.Bool("rollover", false, "Tambahkan sisa anggaran ke periode berikutnya")
+		setCmd.Parse(args)
+
+		period, err := parsePeriod(*periodValue)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if *amount == "" {
+			fmt.Println("Error: amount wajib diisi.")
+			return
+		}
+		config := loadData()
+		budget, err := parseMoney(*amount, config.BaseCurrency)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		b := Budget{Category: *category, Period: period, Amount: budget, Rollover: *rollover, Since: time.Now()}
+		if budget.Minor == 0 {
+			// budget --amount 0 menghapus anggaran, seperti sebelumnya.
+			removeBudget(&config, b.Category, b.Period)
+			saveData(config)
+			fmt.Printf("%s dihapus.\n", capitalize(b.name()))
+			return
+		}
+		setBudget(&config, b)
+		saveData(config)
+		fmt.Printf("%s diatur sebesar %s\n", capitalize(b.name()), budget)
+
+	case "remove":
+		removeCmd := flag.NewFlagSet("budget remove", flag.ExitOnError)
+		category := removeCmd.String("category", "", "Kategori (kosong: semua kategori)")
+		periodValue := removeCmd.String("period", "monthly", "Periode: weekly, monthly atau yearly")
+		removeCmd.Parse(args)
+
+		period, err := parsePeriod(*periodValue)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		config := loadData()
+		b := Budget{Category: *category, Period: period}
+		if !removeBudget(&config, b.Category, b.Period) {
+			fmt.Printf("Error: %s tidak ditemukan.\n", capitalize(b.name()))
+			return
+		}
+		saveData(config)
+		fmt.Printf("%s dihapus.\n", capitalize(b.name()))
+
+	case "status":
+		showBudgetStatus()
+
+	case "alerts":
+		alertsCmd := flag.NewFlagSet("budget alerts", flag.ExitOnError)
+		at := alertsCmd.String("at", "", "Ambang peringatan dalam persen, misalnya 80,100")
+		alertsCmd.Parse(args)
+
+		config := loadData()
+		if *at != "" {
+			thresholds, err := parseThresholds(*at)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			config.Thresholds = thresholds
+			saveData(config)
+		}
+		var parts []string
+		for _, threshold := range config.thresholds() {
+			parts = append(parts, strconv.Itoa(threshold)+"%")
+		}
+		fmt.Printf("Peringatan anggaran muncul pada: %s\n", strings.Join(parts, ", "))
+
+	default:
+		fmt.Printf("Perintah budget tidak dikenal: %s\n", sub)
+		fmt.Println("Gunakan: expense-tracker budget [set|remove|status|alerts] [options]")
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
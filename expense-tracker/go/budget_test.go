@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func utcDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodBounds(t *testing.T) {
+	tests := []struct {
+		period      Period
+		at          time.Time
+		start, next time.Time
+	}{
+		// 2026-10-14 adalah hari Rabu; minggu dimulai hari Senin.
+		{PeriodWeekly, time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC), utcDate(2026, 10, 12), utcDate(2026, 10, 19)},
+		{PeriodWeekly, utcDate(2026, 10, 12), utcDate(2026, 10, 12), utcDate(2026, 10, 19)},
+		{PeriodWeekly, utcDate(2026, 10, 18), utcDate(2026, 10, 12), utcDate(2026, 10, 19)},
+		{PeriodWeekly, utcDate(2027, 1, 1), utcDate(2026, 12, 28), utcDate(2027, 1, 4)},
+		{PeriodMonthly, utcDate(2026, 2, 28), utcDate(2026, 2, 1), utcDate(2026, 3, 1)},
+		{PeriodMonthly, utcDate(2026, 12, 31), utcDate(2026, 12, 1), utcDate(2027, 1, 1)},
+		{PeriodYearly, utcDate(2026, 7, 4), utcDate(2026, 1, 1), utcDate(2027, 1, 1)},
+	}
+	for _, tt := range tests {
+		start, next := tt.period.bounds(tt.at)
+		if !start.Equal(tt.start) || !next.Equal(tt.next) {
+			t.Errorf("%s.bounds(%s) = %s, %s; want %s, %s", tt.period, tt.at.Format(time.DateOnly),
+				start.Format(time.DateOnly), next.Format(time.DateOnly), tt.start.Format(time.DateOnly), tt.next.Format(time.DateOnly))
+		}
+	}
+}
+
+func usd(minor int64) Money { return Money{Minor: minor, Currency: "USD"} }
+
+func TestBudgetUsage(t *testing.T) {
+	config := newConfig()
+	config.Rates = Rates{"EUR": "1.10"}
+	expenses := []Expense{
+		{Date: utcDate(2026, 8, 10), Amount: usd(4000), Category: "Food"},
+		{Date: utcDate(2026, 9, 5), Amount: usd(12000), Category: "Food"},
+		{Date: utcDate(2026, 10, 3), Amount: usd(3000), Category: "food"},
+		{Date: utcDate(2026, 10, 9), Amount: Money{Minor: 1000, Currency: "EUR"}, Category: "Food"},
+		{Date: utcDate(2026, 10, 12), Amount: usd(9900), Category: "Travel"},
+	}
+	at := utcDate(2026, 10, 15)
+
+	tests := []struct {
+		name   string
+		budget Budget
+		carry  int64
+		limit  int64
+		spent  int64
+	}{
+		{
+			name:   "monthly category without rollover",
+			budget: Budget{Category: "Food", Period: PeriodMonthly, Amount: usd(10000)},
+			limit:  10000, spent: 4100,
+		},
+		{
+			// September melebihi anggaran $20, yang tidak mengurangi
+			// anggaran Oktober.
+			name:   "rollover does not carry overspending",
+			budget: Budget{Category: "Food", Period: PeriodMonthly, Amount: usd(10000), Rollover: true, Since: utcDate(2026, 9, 20)},
+			limit:  10000, spent: 4100,
+		},
+		{
+			// Agustus menyisakan $60 dan kelebihan September memakai $20
+			// darinya.
+			name:   "overspending uses up what was carried",
+			budget: Budget{Category: "Food", Period: PeriodMonthly, Amount: usd(10000), Rollover: true, Since: utcDate(2026, 8, 20)},
+			carry:  4000, limit: 14000, spent: 4100,
+		},
+		{
+			name:   "rollover carries what is left",
+			budget: Budget{Category: "Food", Period: PeriodMonthly, Amount: usd(20000), Rollover: true, Since: utcDate(2026, 8, 1)},
+			carry:  24000, limit: 44000, spent: 4100,
+		},
+		{
+			name:   "rollover starting this period",
+			budget: Budget{Category: "Food", Period: PeriodMonthly, Amount: usd(20000), Rollover: true, Since: utcDate(2026, 10, 1)},
+			limit:  20000, spent: 4100,
+		},
+		{
+			name:   "weekly for every category",
+			budget: Budget{Period: PeriodWeekly, Amount: usd(5000)},
+			limit:  5000, spent: 9900,
+		},
+		{
+			name:   "yearly for every category",
+			budget: Budget{Period: PeriodYearly, Amount: usd(100000)},
+			limit:  100000, spent: 30000,
+		},
+	}
+	for _, tt := range tests {
+		u, err := config.usage(tt.budget, expenses, at)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if u.Carry != usd(tt.carry) || u.Limit != usd(tt.limit) || u.Spent != usd(tt.spent) {
+			t.Errorf("%s: carry %s, limit %s, spent %s; want %s, %s, %s", tt.name,
+				u.Carry, u.Limit, u.Spent, usd(tt.carry), usd(tt.limit), usd(tt.spent))
+		}
+	}
+}
+
+func TestReachedThreshold(t *testing.T) {
+	tests := []struct {
+		thresholds   []int
+		spent, limit int64
+		want         int
+	}{
+		{nil, 7999, 10000, 0},
+		{nil, 8000, 10000, 80},
+		{nil, 10000, 10000, 100},
+		{nil, 25000, 10000, 100},
+		{[]int{50, 75, 90}, 7600, 10000, 75},
+		{[]int{90, 50}, 9500, 10000, 90},
+		{nil, 0, 0, 0},
+		{nil, 100, 0, 100},
+	}
+	for _, tt := range tests {
+		config := newConfig()
+		config.Thresholds = tt.thresholds
+		u := budgetUsage{Spent: usd(tt.spent), Limit: usd(tt.limit)}
+		if got := config.reachedThreshold(u); got != tt.want {
+			t.Errorf("thresholds %v, spent %d of %d: reached %d, want %d", tt.thresholds, tt.spent, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 )
@@ -15,17 +17,39 @@ type Expense struct {
 	ID          int       `json:"id"`
 	Date        time.Time `json:"date"`
 	Description string    `json:"description"`
-	Amount      float64   `json:"amount"`
+	Amount      Money     `json:"amount"`
 	Category    string    `json:"category"`
 }
 
 type Config struct {
-	Expenses []Expense `json:"expenses"`
-	NextID   int       `json:"next_id"`
-	Budget   float64   `json:"budget"` // Anggaran bulanan
+	Version      int       `json:"version"`
+	Expenses     []Expense `json:"expenses"`
+	NextID       int       `json:"next_id"`
+	BaseCurrency string    `json:"base_currency"` // Mata uang untuk ringkasan dan anggaran
+	Budgets      []Budget  `json:"budgets,omitempty"`
+	Thresholds   []int     `json:"alert_thresholds,omitempty"` // Persentase pemakaian anggaran yang memicu peringatan
+	Rates        Rates     `json:"rates,omitempty"`
 }
 
-const fileName = "expenses.json"
+const (
+	fileName = "expenses.json"
+	// dataVersion adalah versi format expenses.json. File tanpa versi
+	// menyimpan jumlah sebagai float64 dan versi 2 hanya punya satu
+	// anggaran bulanan; keduanya dimigrasikan saat dibaca.
+	dataVersion = 3
+	// defaultCurrency adalah mata uang dasar file baru dan mata uang data
+	// lama, yang selalu ditampilkan dengan tanda $.
+	defaultCurrency = "USD"
+)
+
+func newConfig() Config {
+	return Config{
+		Version:      dataVersion,
+		Expenses:     []Expense{},
+		NextID:       1,
+		BaseCurrency: defaultCurrency,
+	}
+}
 
 // --- Storage Logic ---
 
@@ -33,14 +57,94 @@ func loadData() Config {
 	file, err := os.ReadFile(fileName)
 	if err != nil {
 		// Jika file tidak ada, kembalikan konfigurasi default
-		return Config{Expenses: []Expense{}, NextID: 1, Budget: 0}
+		return newConfig()
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(file, &header); err != nil {
+		return newConfig()
+	}
+	if header.Version < 2 {
+		return migrateData(file)
 	}
 
 	var config Config
 	err = json.Unmarshal(file, &config)
 	if err != nil {
-		return Config{Expenses: []Expense{}, NextID: 1, Budget: 0}
+		return newConfig()
+	}
+	if config.Version < dataVersion {
+		return migrateBudget(file, config)
+	}
+	return config
+}
+
+// legacyConfig adalah format expenses.json sebelum versi 2, dengan jumlah
+// float64 tanpa mata uang.
+type legacyConfig struct {
+	Expenses []struct {
+		ID          int       `json:"id"`
+		Date        time.Time `json:"date"`
+		Description string    `json:"description"`
+		Amount      float64   `json:"amount"`
+		Category    string    `json:"category"`
+	} `json:"expenses"`
+	NextID int     `json:"next_id"`
+	Budget float64 `json:"budget"`
+}
+
+// migrateData mengubah file lama ke format saat ini: jumlah dibulatkan ke
+// sen dan dicatat dalam USD. File lama disimpan sebagai cadangan.
+func migrateData(file []byte) Config {
+	var legacy legacyConfig
+	if err := json.Unmarshal(file, &legacy); err != nil {
+		return newConfig()
+	}
+
+	config := newConfig()
+	if legacy.NextID > 0 {
+		config.NextID = legacy.NextID
+	}
+	if budget := moneyFromFloat(legacy.Budget, config.BaseCurrency); budget.Minor > 0 {
+		config.Budgets = []Budget{{Period: PeriodMonthly, Amount: budget, Since: time.Now()}}
+	}
+	for _, e := range legacy.Expenses {
+		config.Expenses = append(config.Expenses, Expense{
+			ID:          e.ID,
+			Date:        e.Date,
+			Description: e.Description,
+			Amount:      moneyFromFloat(e.Amount, config.BaseCurrency),
+			Category:    e.Category,
+		})
+	}
+
+	backup := fileName + ".v1.bak"
+	if err := os.WriteFile(backup, file, 0644); err != nil {
+		fmt.Printf("Gagal menyimpan cadangan %s: %v\n", backup, err)
+		return config
 	}
+	saveData(config)
+	fmt.Printf("Catatan: %s dimigrasikan ke format versi %d (jumlah dalam %s); file lama disimpan sebagai %s\n", fileName, dataVersion, config.BaseCurrency, backup)
+	return config
+}
+
+// migrateBudget mengubah anggaran bulanan tunggal dari format versi 2
+// menjadi anggaran bulanan untuk semua kategori.
+func migrateBudget(file []byte, config Config) Config {
+	var v2 struct {
+		Budget Money `json:"budget"`
+	}
+	if err := json.Unmarshal(file, &v2); err != nil {
+		return config
+	}
+	if v2.Budget.Minor > 0 {
+		setBudget(&config, Budget{Period: PeriodMonthly, Amount: v2.Budget, Since: time.Now()})
+	}
+	config.Version = dataVersion
+	saveData(config)
+	fmt.Printf("Catatan: %s dimigrasikan ke format versi %d (anggaran bulanan menjadi anggaran semua kategori)\n", fileName, dataVersion)
 	return config
 }
 
@@ -58,13 +162,26 @@ func saveData(config Config) {
 
 // --- Features ---
 
-func addExpense(desc string, amount float64, category string) {
-	if amount <= 0 {
+func addExpense(desc string, amountValue string, currency string, category string) {
+	config := loadData()
+	if currency == "" {
+		currency = config.BaseCurrency
+	}
+	currency, err := parseCurrency(currency)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	amount, err := parseMoney(amountValue, currency)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if amount.Minor <= 0 {
 		fmt.Println("Error: Jumlah (amount) harus bernilai positif.")
 		return
 	}
 
-	config := loadData()
 	newExpense := Expense{
 		ID:          config.NextID,
 		Date:        time.Now(),
@@ -73,43 +190,41 @@ func addExpense(desc string, amount float64, category string) {
 		Category:    category,
 	}
 
+	before := config.Expenses
 	config.Expenses = append(config.Expenses, newExpense)
 	config.NextID++
 
-	// Cek Anggaran Bulanan
-	currentMonth := time.Now().Month()
-	currentYear := time.Now().Year()
-	var monthlyTotal float64
-	for _, e := range config.Expenses {
-		if e.Date.Month() == currentMonth && e.Date.Year() == currentYear {
-			monthlyTotal += e.Amount
-		}
-	}
-
 	saveData(config)
 	fmt.Printf("Pengeluaran berhasil ditambahkan (ID: %d)\n", newExpense.ID)
 
-	if config.Budget > 0 && monthlyTotal > config.Budget {
-		fmt.Printf("⚠️ PERINGATAN: Anda telah melebihi anggaran bulanan sebesar $%.2f! (Terpakai: $%.2f)\n", config.Budget, monthlyTotal)
-	}
+	// Cek Anggaran
+	warnBudgets(before, config, newExpense.Date)
 }
 
-func updateExpense(id int, desc string, amount float64, category string) {
+func updateExpense(id int, desc string, amountValue string, currency string, category string) {
 	config := loadData()
+	before := slices.Clone(config.Expenses)
 	found := false
+	var date time.Time
 
 	for i, e := range config.Expenses {
 		if e.ID == id {
 			if desc != "" {
 				config.Expenses[i].Description = desc
 			}
-			if amount > 0 {
+			if amountValue != "" || currency != "" {
+				amount, err := changeAmount(e.Amount, amountValue, currency)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					return
+				}
 				config.Expenses[i].Amount = amount
 			}
 			if category != "" {
 				config.Expenses[i].Category = category
 			}
 			found = true
+			date = e.Date
 			break
 		}
 	}
@@ -121,6 +236,34 @@ func updateExpense(id int, desc string, amount float64, category string) {
 
 	saveData(config)
 	fmt.Printf("Pengeluaran ID %d berhasil diperbarui.\n", id)
+	warnBudgets(before, config, date)
+}
+
+// changeAmount menerapkan --amount dan --currency pada jumlah lama. Bila
+// hanya mata uangnya yang diganti, angka yang tertulis tetap sama.
+func changeAmount(old Money, amountValue string, currency string) (Money, error) {
+	if currency == "" {
+		currency = old.Currency
+	}
+	currency, err := parseCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if amountValue == "" {
+		amount, err := parseMoney(old.Decimal(), currency)
+		if err != nil {
+			return Money{}, fmt.Errorf("%v; sertakan juga --amount", err)
+		}
+		return amount, nil
+	}
+	amount, err := parseMoney(amountValue, currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if amount.Minor <= 0 {
+		return Money{}, errors.New("Jumlah (amount) harus bernilai positif.")
+	}
+	return amount, nil
 }
 
 func listExpenses(categoryFilter string) {
@@ -130,14 +273,14 @@ func listExpenses(categoryFilter string) {
 		return
 	}
 
-	fmt.Printf("%-5s %-12s %-20s %-10s %-10s\n", "ID", "Tanggal", "Deskripsi", "Jumlah", "Kategori")
-	fmt.Println(strings.Repeat("-", 65))
+	fmt.Printf("%-5s %-12s %-20s %-16s %-10s\n", "ID", "Tanggal", "Deskripsi", "Jumlah", "Kategori")
+	fmt.Println(strings.Repeat("-", 71))
 
 	for _, e := range config.Expenses {
 		if categoryFilter != "" && !strings.EqualFold(e.Category, categoryFilter) {
 			continue
 		}
-		fmt.Printf("%-5d %-12s %-20s $%-10.2f %-10s\n",
+		fmt.Printf("%-5d %-12s %-20s %-16s %-10s\n",
 			e.ID, e.Date.Format("2006-01-02"), e.Description, e.Amount, e.Category)
 	}
 }
@@ -162,27 +305,59 @@ func deleteExpense(id int) {
 	fmt.Println("Pengeluaran berhasil dihapus.")
 }
 
-func showSummary(month int) {
+// showSummary menjumlahkan pengeluaran satu bulan, satu tahun atau
+// seluruhnya. Bulan tanpa tahun berarti bulan itu pada tahun ini.
+func showSummary(month int, year int) {
 	config := loadData()
-	var total float64
-	year := time.Now().Year()
+	if month < 0 || month > 12 {
+		fmt.Println("Error: Bulan tidak valid (1-12).")
+		return
+	}
+	if month > 0 && year == 0 {
+		year = time.Now().Year()
+	}
 
-	if month > 0 {
-		if month < 1 || month > 12 {
-			fmt.Println("Error: Bulan tidak valid (1-12).")
-			return
-		}
+	var expenses []Expense
+	label := "Total seluruh pengeluaran"
+	switch {
+	case month > 0:
 		for _, e := range config.Expenses {
 			if int(e.Date.Month()) == month && e.Date.Year() == year {
-				total += e.Amount
+				expenses = append(expenses, e)
 			}
 		}
-		fmt.Printf("Total pengeluaran untuk %s: $%.2f\n", time.Month(month).String(), total)
-	} else {
+		label = fmt.Sprintf("Total pengeluaran untuk %s %d", time.Month(month), year)
+	case year != 0:
 		for _, e := range config.Expenses {
-			total += e.Amount
+			if e.Date.Year() == year {
+				expenses = append(expenses, e)
+			}
+		}
+		label = fmt.Sprintf("Total pengeluaran untuk tahun %d", year)
+	default:
+		expenses = config.Expenses
+	}
+
+	total, err := config.totalInBase(expenses)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("%s: %s\n", label, total)
+
+	// Pengeluaran dalam mata uang lain dirinci beserta nilainya dalam mata
+	// uang dasar.
+	byCurrency := map[string]int64{}
+	for _, e := range expenses {
+		byCurrency[e.Amount.Currency] += e.Amount.Minor
+	}
+	_, hasBase := byCurrency[config.BaseCurrency]
+	if len(byCurrency) > 1 || len(byCurrency) == 1 && !hasBase {
+		for _, currency := range sortedKeys(byCurrency) {
+			amount := Money{Minor: byCurrency[currency], Currency: currency}
+			converted, _ := config.toBase(amount)
+			fmt.Printf("  %s: %s (= %s)\n", currency, amount, converted)
 		}
-		fmt.Printf("Total seluruh pengeluaran: $%.2f\n", total)
 	}
 }
 
@@ -200,20 +375,83 @@ func exportCSV() {
 	}
 	defer csvFile.Close()
 
-	fmt.Fprintln(csvFile, "ID,Date,Description,Amount,Category")
+	fmt.Fprintln(csvFile, "ID,Date,Description,Amount,Currency,Category")
 	for _, e := range config.Expenses {
-		fmt.Fprintf(csvFile, "%d,%s,%s,%.2f,%s\n",
-			e.ID, e.Date.Format("2006-01-02"), e.Description, e.Amount, e.Category)
+		fmt.Fprintf(csvFile, "%d,%s,%s,%s,%s,%s\n",
+			e.ID, e.Date.Format("2006-01-02"), e.Description, e.Amount.Decimal(), e.Amount.Currency, e.Category)
 	}
 	fmt.Println("Data berhasil diekspor ke file expenses_export.csv")
 }
 
+func setRate(currency string, value string, remove bool) {
+	config := loadData()
+	currency, err := parseCurrency(currency)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if currency == config.BaseCurrency {
+		fmt.Printf("Error: %s adalah mata uang dasar; kursnya selalu 1.\n", currency)
+		return
+	}
+
+	if remove {
+		if _, ok := config.Rates[currency]; !ok {
+			fmt.Printf("Error: Kurs %s belum diatur.\n", currency)
+			return
+		}
+		delete(config.Rates, currency)
+		saveData(config)
+		fmt.Printf("Kurs %s berhasil dihapus.\n", currency)
+		return
+	}
+
+	rate, err := parseRate(value)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if config.Rates == nil {
+		config.Rates = Rates{}
+	}
+	config.Rates[currency] = formatRate(rate)
+	saveData(config)
+	fmt.Printf("Kurs diatur: 1 %s = %s %s\n", currency, config.Rates[currency], config.BaseCurrency)
+}
+
+func listRates() {
+	config := loadData()
+	fmt.Printf("Mata uang dasar: %s\n", config.BaseCurrency)
+	if len(config.Rates) == 0 {
+		fmt.Println("Belum ada kurs yang diatur.")
+		return
+	}
+	for _, currency := range sortedKeys(config.Rates) {
+		fmt.Printf("  1 %s = %s %s\n", currency, config.Rates[currency], config.BaseCurrency)
+	}
+}
+
+func setBase(currency string) {
+	config := loadData()
+	currency, err := parseCurrency(currency)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if err := config.setBaseCurrency(currency); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	saveData(config)
+	fmt.Printf("Mata uang dasar diatur ke %s; kurs dan anggaran telah dihitung ulang.\n", currency)
+}
+
 // --- Main CLI Handler ---
 
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Gunakan: expense-tracker [command] [options]")
-		fmt.Println("Perintah tersedia: add, list, delete, update, summary, budget, export")
+		fmt.Println("Perintah tersedia: add, list, delete, update, summary, report, budget, check, rate, base, export")
 		return
 	}
 
@@ -223,21 +461,23 @@ func main() {
 	case "add":
 		addCmd := flag.NewFlagSet("add", flag.ExitOnError)
 		desc := addCmd.String("description", "", "Deskripsi pengeluaran")
-		amount := addCmd.Float64("amount", 0, "Jumlah pengeluaran")
+		amount := addCmd.String("amount", "", "Jumlah pengeluaran, misalnya 12.50")
+		currency := addCmd.String("currency", "", "Mata uang, misalnya IDR (default: mata uang dasar)")
 		category := addCmd.String("category", "General", "Kategori pengeluaran")
 		addCmd.Parse(os.Args[2:])
 
-		if *desc == "" || *amount <= 0 {
+		if *desc == "" || *amount == "" {
 			fmt.Println("Error: description dan amount (positif) wajib diisi.")
 			return
 		}
-		addExpense(*desc, *amount, *category)
+		addExpense(*desc, *amount, *currency, *category)
 
 	case "update":
 		updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
 		id := updateCmd.Int("id", 0, "ID pengeluaran yang akan diubah")
 		desc := updateCmd.String("description", "", "Deskripsi baru")
-		amount := updateCmd.Float64("amount", 0, "Jumlah baru")
+		amount := updateCmd.String("amount", "", "Jumlah baru")
+		currency := updateCmd.String("currency", "", "Mata uang baru")
 		category := updateCmd.String("category", "", "Kategori baru")
 		updateCmd.Parse(os.Args[2:])
 
@@ -245,7 +485,7 @@ func main() {
 			fmt.Println("Error: ID wajib diisi.")
 			return
 		}
-		updateExpense(*id, *desc, *amount, *category)
+		updateExpense(*id, *desc, *amount, *currency, *category)
 
 	case "list":
 		listCmd := flag.NewFlagSet("list", flag.ExitOnError)
@@ -266,17 +506,44 @@ func main() {
 	case "summary":
 		summaryCmd := flag.NewFlagSet("summary", flag.ExitOnError)
 		month := summaryCmd.Int("month", 0, "Bulan spesifik (1-12)")
+		year := summaryCmd.Int("year", 0, "Tahun spesifik (bawaan: tahun ini bila month diisi)")
 		summaryCmd.Parse(os.Args[2:])
-		showSummary(*month)
+		showSummary(*month, *year)
+
+	case "report":
+		runReportCommand(os.Args[2:])
 
 	case "budget":
-		budgetCmd := flag.NewFlagSet("budget", flag.ExitOnError)
-		amount := budgetCmd.Float64("amount", 0, "Atur anggaran bulanan")
-		budgetCmd.Parse(os.Args[2:])
-		config := loadData()
-		config.Budget = *amount
-		saveData(config)
-		fmt.Printf("Anggaran bulanan diatur sebesar $%.2f\n", *amount)
+		runBudgetCommand(os.Args[2:])
+
+	case "check":
+		checkBudgets()
+
+	case "rate":
+		rateCmd := flag.NewFlagSet("rate", flag.ExitOnError)
+		currency := rateCmd.String("currency", "", "Mata uang yang kursnya diatur, misalnya EUR")
+		value := rateCmd.String("value", "", "Nilai 1 unit mata uang itu dalam mata uang dasar")
+		remove := rateCmd.Bool("delete", false, "Hapus kurs mata uang itu")
+		rateCmd.Parse(os.Args[2:])
+
+		switch {
+		case *currency == "":
+			listRates()
+		case *value == "" && !*remove:
+			fmt.Println("Error: value atau delete wajib diisi bersama currency.")
+		default:
+			setRate(*currency, *value, *remove)
+		}
+
+	case "base":
+		baseCmd := flag.NewFlagSet("base", flag.ExitOnError)
+		currency := baseCmd.String("currency", "", "Mata uang dasar baru, misalnya IDR")
+		baseCmd.Parse(os.Args[2:])
+		if *currency == "" {
+			fmt.Println("Error: currency wajib diisi.")
+			return
+		}
+		setBase(*currency)
 
 	case "export":
 		exportCSV()
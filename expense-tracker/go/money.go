@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// --- Money ---
+
+// Money adalah jumlah uang yang eksak, disimpan dalam satuan terkecil mata
+// uangnya (sen untuk USD dan EUR, rupiah untuk IDR) agar tidak ada
+// pembulatan yang bergeser seperti pada float64.
+type Money struct {
+	Minor    int64  `json:"minor"`
+	Currency string `json:"currency"`
+}
+
+type currencyInfo struct {
+	Symbol   string
+	Decimals int
+}
+
+// currencies berisi mata uang yang dikenal. Kode tiga huruf lain tetap
+// diterima dengan dua angka desimal dan kodenya sebagai simbol.
+var currencies = map[string]currencyInfo{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"IDR": {"Rp", 0},
+	"JPY": {"¥", 0},
+	"SGD": {"S$", 2},
+	"MYR": {"RM", 2},
+	"AUD": {"A$", 2},
+}
+
+func currencyOf(code string) currencyInfo {
+	if info, ok := currencies[code]; ok {
+		return info
+	}
+	return currencyInfo{Symbol: code + " ", Decimals: 2}
+}
+
+// parseCurrency menormalkan kode mata uang seperti "idr" menjadi "IDR".
+func parseCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("kode mata uang %q tidak valid (gunakan kode tiga huruf seperti IDR, USD atau EUR)", code)
+	}
+	return code, nil
+}
+
+// parseMoney membaca jumlah desimal seperti "12.50" atau "15000" tanpa
+// melewati float, sehingga nilainya tepat sama dengan yang ditulis.
+func parseMoney(value, currency string) (Money, error) {
+	info := currencyOf(currency)
+	whole, frac, hasFrac := strings.Cut(strings.TrimSpace(value), ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(frac, "0123456789") != "" || hasFrac && frac == "" {
+		return Money{}, fmt.Errorf("jumlah %q tidak valid (contoh: 12.50)", value)
+	}
+	if len(frac) > info.Decimals {
+		return Money{}, fmt.Errorf("jumlah %q memiliki terlalu banyak angka desimal untuk %s (maksimal %d)", value, currency, info.Decimals)
+	}
+	digits := whole + frac + strings.Repeat("0", info.Decimals-len(frac))
+	minor, ok := new(big.Int).SetString(digits, 10)
+	if !ok || !minor.IsInt64() {
+		return Money{}, fmt.Errorf("jumlah %q terlalu besar", value)
+	}
+	return Money{Minor: minor.Int64(), Currency: currency}, nil
+}
+
+// moneyFromFloat mengubah jumlah lama dalam float64 ke satuan terkecil,
+// dibulatkan ke satuan terdekat.
+func moneyFromFloat(amount float64, currency string) Money {
+	rat := new(big.Rat)
+	rat.SetFloat64(amount)
+	return Money{Minor: roundRat(rat.Mul(rat, scale(currency))), Currency: currency}
+}
+
+// Decimal menulis jumlah tanpa simbol, seperti 1234.50, untuk CSV.
+func (m Money) Decimal() string {
+	decimals := currencyOf(m.Currency).Decimals
+	sign, minor := "", m.Minor
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	s := fmt.Sprintf("%0*d", decimals+1, minor)
+	if decimals == 0 {
+		return sign + s
+	}
+	return sign + s[:len(s)-decimals] + "." + s[len(s)-decimals:]
+}
+
+// String menulis jumlah dengan simbol dan pemisah ribuan, seperti $1,234.50
+// atau Rp150,000.
+func (m Money) String() string {
+	s := m.Decimal()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	if hasFrac {
+		whole += "." + frac
+	}
+	return sign + currencyOf(m.Currency).Symbol + whole
+}
+
+// --- Exchange Rates ---
+
+// Rates memetakan kode mata uang ke nilai satu unitnya dalam mata uang
+// dasar, misalnya {"USD": "15800"} bila mata uang dasarnya IDR. Nilai
+// disimpan sebagai teks desimal (atau pecahan seperti 1/15800) agar tetap
+// eksak.
+type Rates map[string]string
+
+func parseRate(value string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("kurs %q tidak valid (gunakan angka positif seperti 15800 atau 1.08)", value)
+	}
+	return rate, nil
+}
+
+// formatRate menulis kurs sebagai desimal bila bisa ditulis tepat, dan
+// sebagai pecahan bila tidak.
+func formatRate(rate *big.Rat) string {
+	for digits := 0; digits <= 12; digits++ {
+		s := rate.FloatString(digits)
+		if back, _ := new(big.Rat).SetString(s); back.Cmp(rate) == 0 {
+			return s
+		}
+	}
+	return rate.RatString()
+}
+
+// rate mengembalikan nilai satu unit currency dalam mata uang dasar.
+func (config Config) rate(currency string) (*big.Rat, error) {
+	if currency == config.BaseCurrency {
+		return big.NewRat(1, 1), nil
+	}
+	value, ok := config.Rates[currency]
+	if !ok {
+		return nil, fmt.Errorf("kurs %s belum diatur; gunakan: expense-tracker rate --currency %s --value <nilai dalam %s>", currency, currency, config.BaseCurrency)
+	}
+	return parseRate(value)
+}
+
+// toBase mengonversi jumlah ke mata uang dasar dengan kurs lokal.
+func (config Config) toBase(m Money) (Money, error) {
+	if m.Currency == config.BaseCurrency {
+		return m, nil
+	}
+	rate, err := config.rate(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	value := new(big.Rat).SetInt64(m.Minor)
+	value.Quo(value, scale(m.Currency))
+	value.Mul(value, rate)
+	value.Mul(value, scale(config.BaseCurrency))
+	return Money{Minor: roundRat(value), Currency: config.BaseCurrency}, nil
+}
+
+// totalInBase menjumlahkan pengeluaran per mata uang lalu mengonversi tiap
+// jumlah sekali saja, sehingga pembulatan tidak menumpuk per entri.
+func (config Config) totalInBase(expenses []Expense) (Money, error) {
+	byCurrency := map[string]int64{}
+	for _, e := range expenses {
+		byCurrency[e.Amount.Currency] += e.Amount.Minor
+	}
+	total := Money{Currency: config.BaseCurrency}
+	for _, currency := range sortedKeys(byCurrency) {
+		converted, err := config.toBase(Money{Minor: byCurrency[currency], Currency: currency})
+		if err != nil {
+			return Money{}, err
+		}
+		total.Minor += converted.Minor
+	}
+	return total, nil
+}
+
+// setBaseCurrency mengganti mata uang dasar. Kurs lain dan anggaran
+// dihitung ulang terhadap mata uang dasar yang baru, sehingga kurs mata
+// uang baru itu harus sudah diatur.
+func (config *Config) setBaseCurrency(currency string) error {
+	if currency == config.BaseCurrency {
+		return nil
+	}
+	pivot, err := config.rate(currency)
+	if err != nil {
+		return err
+	}
+
+	rates := Rates{}
+	for code, value := range config.Rates {
+		if code == currency {
+			continue
+		}
+		rate, err := parseRate(value)
+		if err != nil {
+			return err
+		}
+		rates[code] = formatRate(rate.Quo(rate, pivot))
+	}
+	rates[config.BaseCurrency] = formatRate(new(big.Rat).Inv(pivot))
+
+	config.BaseCurrency = currency
+	config.Rates = rates
+	for i, b := range config.Budgets {
+		if config.Budgets[i].Amount, err = config.toBase(b.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scale adalah 10^desimal mata uang: jumlah satuan terkecil per unit.
+func scale(currency string) *big.Rat {
+	exp := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currencyOf(currency).Decimals)), nil)
+	return new(big.Rat).SetInt(exp)
+}
+
+// roundRat membulatkan ke bilangan bulat terdekat, setengah menjauhi nol.
+func roundRat(r *big.Rat) int64 {
+	num := new(big.Int).Abs(r.Num())
+	q, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
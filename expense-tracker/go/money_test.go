@@ -0,0 +1,199 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value, currency string
+		want            int64
+		wantErr         bool
+	}{
+		{"12.50", "USD", 1250, false},
+		{"12.5", "USD", 1250, false},
+		{"12", "USD", 1200, false},
+		{" 0.01 ", "EUR", 1, false},
+		{"150000", "IDR", 150000, false},
+		{"0.1", "XYZ", 10, false},
+		{"12.505", "USD", 0, true},
+		{"1.5", "IDR", 0, true},
+		{"12.", "USD", 0, true},
+		{".50", "USD", 0, true},
+		{"-5", "USD", 0, true},
+		{"1e3", "USD", 0, true},
+		{"abc", "USD", 0, true},
+		{"99999999999999999999", "USD", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMoney(tt.value, tt.currency)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseMoney(%q, %s) = %v, want an error", tt.value, tt.currency, got)
+			}
+			continue
+		}
+		if err != nil || got != (Money{Minor: tt.want, Currency: tt.currency}) {
+			t.Errorf("parseMoney(%q, %s) = %+v, %v; want %d", tt.value, tt.currency, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyFormatting(t *testing.T) {
+	tests := []struct {
+		money         Money
+		decimal, text string
+	}{
+		{Money{123450, "USD"}, "1234.50", "$1,234.50"},
+		{Money{5, "USD"}, "0.05", "$0.05"},
+		{Money{-123450, "EUR"}, "-1234.50", "-€1,234.50"},
+		{Money{150000, "IDR"}, "150000", "Rp150,000"},
+		{Money{1000000, "JPY"}, "1000000", "¥1,000,000"},
+		{Money{0, "USD"}, "0.00", "$0.00"},
+		{Money{999, "XYZ"}, "9.99", "XYZ 9.99"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.decimal {
+			t.Errorf("%+v.Decimal() = %s, want %s", tt.money, got, tt.decimal)
+		}
+		if got := tt.money.String(); got != tt.text {
+			t.Errorf("%+v.String() = %s, want %s", tt.money, got, tt.text)
+		}
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     int64
+	}{
+		{0.1 + 0.2, "USD", 30},
+		{19.99, "USD", 1999},
+		{1.005, "USD", 100},
+		{2.675, "USD", 267},
+		{150000.4, "IDR", 150000},
+		{150000.5, "IDR", 150001},
+		{-3.335, "USD", -333},
+	}
+	for _, tt := range tests {
+		if got := moneyFromFloat(tt.amount, tt.currency); got.Minor != tt.want || got.Currency != tt.currency {
+			t.Errorf("moneyFromFloat(%v, %s) = %+v, want %d", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestRoundRat(t *testing.T) {
+	tests := []struct {
+		num, denom int64
+		want       int64
+	}{
+		{5, 2, 3},
+		{-5, 2, -3},
+		{7, 3, 2},
+		{8, 3, 3},
+		{-8, 3, -3},
+		{0, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := roundRat(big.NewRat(tt.num, tt.denom)); got != tt.want {
+			t.Errorf("roundRat(%d/%d) = %d, want %d", tt.num, tt.denom, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	tests := []struct {
+		rate *big.Rat
+		want string
+	}{
+		{big.NewRat(15800, 1), "15800"},
+		{big.NewRat(108, 100), "1.08"},
+		{big.NewRat(1, 15800), "1/15800"},
+		{big.NewRat(1, 8), "0.125"},
+	}
+	for _, tt := range tests {
+		if got := formatRate(tt.rate); got != tt.want {
+			t.Errorf("formatRate(%s) = %s, want %s", tt.rate, got, tt.want)
+		}
+		if back, err := parseRate(tt.want); err != nil || back.Cmp(tt.rate) != 0 {
+			t.Errorf("parseRate(%s) = %v, %v; want %s back", tt.want, back, err, tt.rate)
+		}
+	}
+	for _, value := range []string{"0", "-1", "abc", ""} {
+		if _, err := parseRate(value); err == nil {
+			t.Errorf("parseRate(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestToBase(t *testing.T) {
+	config := newConfig()
+	config.BaseCurrency = "IDR"
+	config.Rates = Rates{"USD": "15800", "JPY": "105.5"}
+
+	tests := []struct {
+		money   Money
+		want    int64
+		wantErr bool
+	}{
+		{Money{1250, "USD"}, 197500, false},
+		{Money{1, "USD"}, 158, false},
+		{Money{3, "JPY"}, 317, false},
+		{Money{150000, "IDR"}, 150000, false},
+		{Money{100, "EUR"}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := config.toBase(tt.money)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("toBase(%+v) = %+v, want an error for a missing rate", tt.money, got)
+			}
+			continue
+		}
+		if err != nil || got != (Money{Minor: tt.want, Currency: "IDR"}) {
+			t.Errorf("toBase(%+v) = %+v, %v; want Rp%d", tt.money, got, err, tt.want)
+		}
+	}
+}
+
+func TestTotalInBaseRoundsOncePerCurrency(t *testing.T) {
+	config := newConfig()
+	config.Rates = Rates{"JPY": "0.0067"}
+	// Tiap ¥1 bernilai 0.67 sen: dibulatkan per entri totalnya 3 sen,
+	// dijumlahkan dulu totalnya ¥3 = 2.01 sen, dibulatkan 2 sen.
+	expenses := []Expense{
+		{Amount: Money{1, "JPY"}},
+		{Amount: Money{1, "JPY"}},
+		{Amount: Money{1, "JPY"}},
+		{Amount: Money{1000, "USD"}},
+	}
+	total, err := config.totalInBase(expenses)
+	if err != nil || total != (Money{Minor: 1002, Currency: "USD"}) {
+		t.Errorf("totalInBase = %+v, %v; want $10.02", total, err)
+	}
+}
+
+func TestSetBaseCurrency(t *testing.T) {
+	config := newConfig()
+	config.Rates = Rates{"IDR": "1/15800", "EUR": "1.08"}
+	config.Budgets = []Budget{{Category: "Food", Period: PeriodMonthly, Amount: Money{10000, "USD"}}}
+
+	if err := config.setBaseCurrency("GBP"); err == nil {
+		t.Error("setBaseCurrency to a currency without a rate succeeded")
+	}
+	if err := config.setBaseCurrency("IDR"); err != nil {
+		t.Fatal(err)
+	}
+	if config.BaseCurrency != "IDR" {
+		t.Errorf("base currency = %s, want IDR", config.BaseCurrency)
+	}
+	want := Rates{"USD": "15800", "EUR": "17064"}
+	if len(config.Rates) != len(want) || config.Rates["USD"] != want["USD"] || config.Rates["EUR"] != want["EUR"] {
+		t.Errorf("rates = %v, want %v", config.Rates, want)
+	}
+	if got := config.Budgets[0].Amount; got != (Money{Minor: 1580000, Currency: "IDR"}) {
+		t.Errorf("budget = %+v, want Rp1,580,000", got)
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Reports ---
+
+type GroupBy string
+
+const (
+	GroupByCategory GroupBy = "category"
+	GroupByMonth    GroupBy = "month"
+	GroupByWeek     GroupBy = "week"
+	GroupByDay      GroupBy = "day"
+)
+
+var groupings = []GroupBy{GroupByCategory, GroupByMonth, GroupByWeek, GroupByDay}
+
+func parseGroupBy(value string) (GroupBy, error) {
+	group := GroupBy(strings.ToLower(value))
+	if !slices.Contains(groupings, group) {
+		return "", fmt.Errorf("group-by %q tidak valid (gunakan category, month, week atau day)", value)
+	}
+	return group, nil
+}
+
+func (g GroupBy) label() string {
+	switch g {
+	case GroupByMonth:
+		return "Bulan"
+	case GroupByWeek:
+		return "Minggu"
+	case GroupByDay:
+		return "Tanggal"
+	}
+	return "Kategori"
+}
+
+// bucket mengembalikan awal kelompok waktu yang memuat t, awal kelompok
+// berikutnya dan namanya, seperti 2026-10, 2026-W42 atau 2026-10-16.
+func (g GroupBy) bucket(t time.Time) (time.Time, time.Time, string) {
+	switch g {
+	case GroupByWeek:
+		start, next := PeriodWeekly.bounds(t)
+		year, week := start.ISOWeek()
+		return start, next, fmt.Sprintf("%d-W%02d", year, week)
+	case GroupByDay:
+		year, month, day := t.Date()
+		start := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 0, 1), start.Format("2006-01-02")
+	}
+	start, next := PeriodMonthly.bounds(t)
+	return start, next, start.Format("2006-01")
+}
+
+type reportGroup struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	Total   Money   `json:"total"`
+	Average Money   `json:"average"`
+	Share   float64 `json:"share_percent"`
+}
+
+// monthChange adalah total satu bulan dan perubahannya terhadap bulan
+// sebelumnya. Change kosong bila bulan sebelumnya tidak ada pengeluaran.
+type monthChange struct {
+	Month  string   `json:"month"`
+	Total  Money    `json:"total"`
+	Change *float64 `json:"change_percent"`
+}
+
+type reportExpense struct {
+	Expense
+	InBase Money `json:"in_base"`
+}
+
+// Report adalah ringkasan pengeluaran pada satu rentang tanggal. Semua
+// jumlah di dalamnya dalam mata uang dasar.
+type Report struct {
+	From           string          `json:"from"`
+	To             string          `json:"to"`
+	BaseCurrency   string          `json:"base_currency"`
+	GroupBy        GroupBy         `json:"group_by"`
+	Count          int             `json:"count"`
+	Total          Money           `json:"total"`
+	AverageExpense Money           `json:"average_per_expense"`
+	AverageDay     Money           `json:"average_per_day"`
+	AverageMonth   Money           `json:"average_per_month"`
+	Groups         []reportGroup   `json:"groups"`
+	MonthOverMonth []monthChange   `json:"month_over_month"`
+	Top            []reportExpense `json:"top"`
+}
+
+// divide membagi jumlah dengan n, dibulatkan ke satuan terdekat.
+func (m Money) divide(n int) Money {
+	if n == 0 {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Minor: roundRat(big.NewRat(m.Minor, int64(n))), Currency: m.Currency}
+}
+
+func percentOf(part, whole Money) float64 {
+	if whole.Minor == 0 {
+		return 0
+	}
+	return float64(part.Minor) * 100 / float64(whole.Minor)
+}
+
+// calendarDays menghitung jumlah hari dari from sampai sebelum until tanpa
+// terpengaruh pergantian jam musim panas.
+func calendarDays(from, until time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// buildReport menyusun laporan untuk pengeluaran dari from sampai sebelum
+// until. Tanggal pengeluaran dibaca di zona waktu from, apa pun zona yang
+// tersimpan, agar setiap pengeluaran masuk ke hari, minggu dan bulan yang
+// sama dengan batas rentangnya.
+func (config Config) buildReport(from, until time.Time, groupBy GroupBy, top int) (Report, error) {
+	r := Report{
+		From:         from.Format("2006-01-02"),
+		To:           until.AddDate(0, 0, -1).Format("2006-01-02"),
+		BaseCurrency: config.BaseCurrency,
+		GroupBy:      groupBy,
+		Groups:       []reportGroup{},
+		Top:          []reportExpense{},
+	}
+
+	var expenses []Expense
+	for _, e := range config.Expenses {
+		if !e.Date.Before(from) && e.Date.Before(until) {
+			e.Date = e.Date.In(from.Location())
+			expenses = append(expenses, e)
+		}
+	}
+	total, err := config.totalInBase(expenses)
+	if err != nil {
+		return Report{}, err
+	}
+	r.Count = len(expenses)
+	r.Total = total
+	r.AverageExpense = total.divide(len(expenses))
+	r.AverageDay = total.divide(calendarDays(from, until))
+
+	// Kelompok waktu diisi juga untuk periode tanpa pengeluaran, agar
+	// deretnya tidak melompat.
+	var names []string
+	members := map[string][]Expense{}
+	if groupBy == GroupByCategory {
+		for _, e := range expenses {
+			if _, ok := members[e.Category]; !ok {
+				names = append(names, e.Category)
+			}
+			members[e.Category] = append(members[e.Category], e)
+		}
+	} else {
+		for t := from; t.Before(until); {
+			_, next, name := groupBy.bucket(t)
+			names = append(names, name)
+			t = next
+		}
+		for _, e := range expenses {
+			_, _, name := groupBy.bucket(e.Date)
+			members[name] = append(members[name], e)
+		}
+	}
+	for _, name := range names {
+		sum, err := config.totalInBase(members[name])
+		if err != nil {
+			return Report{}, err
+		}
+		r.Groups = append(r.Groups, reportGroup{
+			Name:    name,
+			Count:   len(members[name]),
+			Total:   sum,
+			Average: sum.divide(len(members[name])),
+			Share:   percentOf(sum, total),
+		})
+	}
+	if groupBy == GroupByCategory {
+		sort.SliceStable(r.Groups, func(i, j int) bool {
+			return r.Groups[i].Total.Minor > r.Groups[j].Total.Minor
+		})
+	}
+
+	byMonth := map[string][]Expense{}
+	for _, e := range expenses {
+		_, _, name := GroupByMonth.bucket(e.Date)
+		byMonth[name] = append(byMonth[name], e)
+	}
+	for t := from; t.Before(until); {
+		_, next, name := GroupByMonth.bucket(t)
+		sum, err := config.totalInBase(byMonth[name])
+		if err != nil {
+			return Report{}, err
+		}
+		change := monthChange{Month: name, Total: sum}
+		if n := len(r.MonthOverMonth); n > 0 && r.MonthOverMonth[n-1].Total.Minor > 0 {
+			prev := r.MonthOverMonth[n-1].Total
+			pct := percentOf(sum, prev) - 100
+			change.Change = &pct
+		}
+		r.MonthOverMonth = append(r.MonthOverMonth, change)
+		t = next
+	}
+	r.AverageMonth = total.divide(len(r.MonthOverMonth))
+
+	for _, e := range expenses {
+		inBase, err := config.toBase(e.Amount)
+		if err != nil {
+			return Report{}, err
+		}
+		r.Top = append(r.Top, reportExpense{Expense: e, InBase: inBase})
+	}
+	sort.SliceStable(r.Top, func(i, j int) bool {
+		return r.Top[i].InBase.Minor > r.Top[j].InBase.Minor
+	})
+	r.Top = r.Top[:min(top, len(r.Top))]
+	return r, nil
+}
+
+func formatChange(change *float64) string {
+	if change == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", *change)
+}
+
+func (r Report) printHeader() {
+	fmt.Printf("Laporan pengeluaran %s s/d %s (dalam %s)\n", r.From, r.To, r.BaseCurrency)
+	fmt.Printf("Total: %s dari %d pengeluaran\n", r.Total, r.Count)
+	fmt.Printf("Rata-rata: %s per pengeluaran, %s per hari, %s per bulan\n", r.AverageExpense, r.AverageDay, r.AverageMonth)
+}
+
+func (r Report) printTop() {
+	if len(r.Top) == 0 {
+		return
+	}
+	fmt.Printf("\n%d pengeluaran terbesar:\n", len(r.Top))
+	fmt.Printf("%-5s %-12s %-20s %-26s %-10s\n", "ID", "Tanggal", "Deskripsi", "Jumlah", "Kategori")
+	fmt.Println(strings.Repeat("-", 81))
+	for _, e := range r.Top {
+		amount := e.Amount.String()
+		if e.Amount.Currency != r.BaseCurrency {
+			amount += " (= " + e.InBase.String() + ")"
+		}
+		fmt.Printf("%-5d %-12s %-20s %-26s %-10s\n",
+			e.ID, e.Date.Format("2006-01-02"), e.Description, amount, e.Category)
+	}
+}
+
+// printTable menampilkan laporan sebagai tabel. Bila dikelompokkan per
+// bulan, perubahan bulanan menjadi kolom tabel kelompok itu sendiri.
+func (r Report) printTable() {
+	r.printHeader()
+
+	if r.GroupBy == GroupByMonth {
+		fmt.Printf("\n%-14s %-7s %-14s %-14s %-7s %s\n", "Bulan", "Jumlah", "Total", "Rata-rata", "Porsi", "Perubahan")
+		fmt.Println(strings.Repeat("-", 68))
+		for i, g := range r.Groups {
+			fmt.Printf("%-14s %-7d %-14s %-14s %5.1f%%  %s\n", g.Name, g.Count, g.Total, g.Average, g.Share, formatChange(r.MonthOverMonth[i].Change))
+		}
+		r.printTop()
+		return
+	}
+
+	fmt.Printf("\n%-14s %-7s %-14s %-14s %s\n", r.GroupBy.label(), "Jumlah", "Total", "Rata-rata", "Porsi")
+	fmt.Println(strings.Repeat("-", 58))
+	for _, g := range r.Groups {
+		fmt.Printf("%-14s %-7d %-14s %-14s %5.1f%%\n", g.Name, g.Count, g.Total, g.Average, g.Share)
+	}
+
+	fmt.Printf("\n%-14s %-14s %s\n", "Bulan", "Total", "Perubahan")
+	fmt.Println(strings.Repeat("-", 38))
+	for _, m := range r.MonthOverMonth {
+		fmt.Printf("%-14s %-14s %s\n", m.Month, m.Total, formatChange(m.Change))
+	}
+
+	r.printTop()
+}
+
+// reportBarWidth adalah panjang batang terpanjang pada grafik laporan.
+const reportBarWidth = 40
+
+// chartBar membuat batang sepanjang value relatif terhadap longest.
+func chartBar(value, longest Money) string {
+	filled := 0
+	if longest.Minor > 0 {
+		filled = int(value.Minor * reportBarWidth / longest.Minor)
+	}
+	return strings.Repeat("#", filled) + strings.Repeat(" ", reportBarWidth-filled)
+}
+
+// printChart menampilkan laporan sebagai grafik batang per kelompok dan
+// per bulan; grafik bulanan memuat perubahan terhadap bulan sebelumnya.
+func (r Report) printChart() {
+	r.printHeader()
+
+	longest := Money{}
+	if r.GroupBy != GroupByMonth {
+		fmt.Printf("\nPer %s:\n", strings.ToLower(r.GroupBy.label()))
+		for _, g := range r.Groups {
+			if g.Total.Minor > longest.Minor {
+				longest = g.Total
+			}
+		}
+		for _, g := range r.Groups {
+			fmt.Printf("%-14s |%s| %s (%.1f%%)\n", g.Name, chartBar(g.Total, longest), g.Total, g.Share)
+		}
+	}
+
+	fmt.Println("\nPer bulan:")
+	longest = Money{}
+	for _, m := range r.MonthOverMonth {
+		if m.Total.Minor > longest.Minor {
+			longest = m.Total
+		}
+	}
+	for _, m := range r.MonthOverMonth {
+		fmt.Printf("%-14s |%s| %s (%s)\n", m.Month, chartBar(m.Total, longest), m.Total, formatChange(m.Change))
+	}
+
+	r.printTop()
+}
+
+// reportRange menentukan rentang laporan dari --from/--to atau --year.
+// Tanpa keduanya, rentangnya dari pengeluaran pertama sampai terakhir.
+// Hasilnya adalah awal hari pertama dan awal hari setelah hari terakhir,
+// keduanya di zona waktu lokal.
+func reportRange(expenses []Expense, fromValue, toValue string, year int) (time.Time, time.Time, error) {
+	if year != 0 {
+		if fromValue != "" || toValue != "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("gunakan --year atau --from/--to, tidak keduanya")
+		}
+		from := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+		return from, from.AddDate(1, 0, 0), nil
+	}
+
+	var from, to time.Time
+	for i, e := range expenses {
+		date := e.Date.In(time.Local)
+		if i == 0 || date.Before(from) {
+			from = date
+		}
+		if i == 0 || date.After(to) {
+			to = date
+		}
+	}
+	var err error
+	if fromValue != "" {
+		if from, err = time.ParseInLocation("2006-01-02", fromValue, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("tanggal from %q tidak valid (gunakan YYYY-MM-DD)", fromValue)
+		}
+	}
+	if toValue != "" {
+		if to, err = time.ParseInLocation("2006-01-02", toValue, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("tanggal to %q tidak valid (gunakan YYYY-MM-DD)", toValue)
+		}
+	}
+	from, _, _ = GroupByDay.bucket(from)
+	_, until, _ := GroupByDay.bucket(to)
+	if !from.Before(until) {
+		return time.Time{}, time.Time{}, fmt.Errorf("tanggal from %s setelah tanggal to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+	return from, until, nil
+}
+
+func runReportCommand(args []string) {
+	reportCmd := flag.NewFlagSet("report", flag.ExitOnError)
+	fromValue := reportCmd.String("from", "", "Tanggal awal (YYYY-MM-DD)")
+	toValue := reportCmd.String("to", "", "Tanggal akhir (YYYY-MM-DD), termasuk")
+	year := reportCmd.Int("year", 0, "Laporkan satu tahun penuh")
+	groupValue := reportCmd.String("group-by", "category", "Kelompokkan per category, month, week atau day")
+	top := reportCmd.Int("top", 5, "Jumlah pengeluaran terbesar yang ditampilkan")
+	format := reportCmd.String("format", "table", "Tampilan: table, chart atau json")
+	reportCmd.Parse(args)
+
+	groupBy, err := parseGroupBy(*groupValue)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if *top < 0 {
+		fmt.Println("Error: top tidak boleh negatif.")
+		return
+	}
+	if !slices.Contains([]string{"table", "chart", "json"}, *format) {
+		fmt.Printf("Error: format %q tidak valid (gunakan table, chart atau json)\n", *format)
+		return
+	}
+
+	config := loadData()
+	if len(config.Expenses) == 0 && *year == 0 && (*fromValue == "" || *toValue == "") {
+		fmt.Println("Belum ada data pengeluaran.")
+		return
+	}
+	from, until, err := reportRange(config.Expenses, *fromValue, *toValue, *year)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	report, err := config.buildReport(from, until, groupBy, *top)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	switch *format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Printf("Gagal memproses data: %v\n", err)
+			return
+		}
+		fmt.Println(string(data))
+	case "chart":
+		report.printChart()
+	default:
+		report.printTable()
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// inZone menjalankan test dengan zona waktu lokal loc.
+func inZone(t *testing.T, loc *time.Location) {
+	t.Helper()
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestReportUsesOneLocation(t *testing.T) {
+	inZone(t, time.FixedZone("WIB", 7*60*60))
+	// Data migrasi menyimpan tanggal dalam UTC: pukul 20.00 UTC tanggal 15
+	// sudah tanggal 16 di zona lokal.
+	config := newConfig()
+	config.Expenses = []Expense{
+		{ID: 1, Date: time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC), Amount: Money{Minor: 500, Currency: "USD"}, Category: "Food"},
+		{ID: 2, Date: time.Date(2026, 10, 20, 10, 0, 0, 0, time.Local), Amount: Money{Minor: 700, Currency: "USD"}, Category: "Food"},
+	}
+
+	tests := []struct {
+		from, to  string
+		wantFrom  string
+		wantUntil string
+	}{
+		{"", "", "2026-10-16", "2026-10-21"},
+		{"", "2026-10-16", "2026-10-16", "2026-10-17"},
+		{"2026-10-16", "", "2026-10-16", "2026-10-21"},
+	}
+	for _, tt := range tests {
+		from, until, err := reportRange(config.Expenses, tt.from, tt.to, 0)
+		if err != nil {
+			t.Errorf("reportRange(%q, %q): %v", tt.from, tt.to, err)
+			continue
+		}
+		for _, bound := range []time.Time{from, until} {
+			if bound.Location() != time.Local || bound.Hour() != 0 {
+				t.Errorf("reportRange(%q, %q) bound %s is not a local midnight", tt.from, tt.to, bound)
+			}
+		}
+		if got := from.Format("2006-01-02"); got != tt.wantFrom {
+			t.Errorf("reportRange(%q, %q) from = %s, want %s", tt.from, tt.to, got, tt.wantFrom)
+		}
+		if got := until.Format("2006-01-02"); got != tt.wantUntil {
+			t.Errorf("reportRange(%q, %q) until = %s, want %s", tt.from, tt.to, got, tt.wantUntil)
+		}
+	}
+
+	from, until, _ := reportRange(config.Expenses, "", "2026-10-16", 0)
+	report, err := config.buildReport(from, until, GroupByDay, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Groups) != 1 || report.Groups[0].Name != "2026-10-16" || report.Groups[0].Count != 1 {
+		t.Errorf("groups = %+v, want the UTC expense on 2026-10-16", report.Groups)
+	}
+}
+
+func reportConfig() Config {
+	config := newConfig()
+	config.Rates = Rates{"EUR": "1.10"}
+	config.Expenses = []Expense{
+		{ID: 1, Date: utcDate(2026, 7, 30), Amount: usd(9999), Category: "Food"},
+		{ID: 2, Date: utcDate(2026, 8, 3), Amount: usd(2000), Category: "Food"},
+		{ID: 3, Date: utcDate(2026, 8, 4), Amount: usd(6000), Category: "Travel"},
+		{ID: 4, Date: utcDate(2026, 8, 17), Amount: Money{Minor: 1000, Currency: "EUR"}, Category: "Food"},
+		{ID: 5, Date: utcDate(2026, 10, 6), Amount: usd(4500), Category: "Bills"},
+		{ID: 6, Date: utcDate(2026, 10, 31), Amount: usd(1500), Category: "Food"},
+		{ID: 7, Date: utcDate(2026, 11, 1), Amount: usd(9999), Category: "Food"},
+	}
+	return config
+}
+
+func TestBuildReportGroups(t *testing.T) {
+	config := reportConfig()
+	from, until := utcDate(2026, 8, 1), utcDate(2026, 11, 1)
+
+	type group struct {
+		name  string
+		count int
+		total int64
+	}
+	tests := []struct {
+		groupBy GroupBy
+		want    []group
+	}{
+		// Kategori diurutkan dari total terbesar; bulan tanpa pengeluaran
+		// tetap muncul.
+		{GroupByCategory, []group{{"Travel", 1, 6000}, {"Food", 3, 4600}, {"Bills", 1, 4500}}},
+		{GroupByMonth, []group{{"2026-08", 3, 9100}, {"2026-09", 0, 0}, {"2026-10", 2, 6000}}},
+	}
+	for _, tt := range tests {
+		report, err := config.buildReport(from, until, tt.groupBy, 5)
+		if err != nil {
+			t.Fatalf("buildReport by %s: %v", tt.groupBy, err)
+		}
+		var got []group
+		for _, g := range report.Groups {
+			got = append(got, group{g.Name, g.Count, g.Total.Minor})
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("groups by %s = %v, want %v", tt.groupBy, got, tt.want)
+		}
+	}
+
+	report, _ := config.buildReport(from, until, GroupByWeek, 5)
+	if n := len(report.Groups); n != 14 || report.Groups[0].Name != "2026-W31" || report.Groups[n-1].Name != "2026-W44" {
+		t.Errorf("weeks = %d from %s to %s, want 14 from 2026-W31 to 2026-W44", n, report.Groups[0].Name, report.Groups[n-1].Name)
+	}
+	report, _ = config.buildReport(from, until, GroupByDay, 5)
+	if n := len(report.Groups); n != 92 || report.Groups[30].Name != "2026-08-31" || report.Groups[30].Count != 0 {
+		t.Errorf("days = %d, day 31 = %+v; want 92 days with empty ones", n, report.Groups[30])
+	}
+}
+
+func TestBuildReportTotals(t *testing.T) {
+	config := reportConfig()
+	report, err := config.buildReport(utcDate(2026, 8, 1), utcDate(2026, 11, 1), GroupByCategory, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Total $151.00 dari 5 pengeluaran dalam 92 hari dan 3 bulan.
+	totals := []struct {
+		name      string
+		got, want Money
+	}{
+		{"total", report.Total, usd(15100)},
+		{"average per expense", report.AverageExpense, usd(3020)},
+		{"average per day", report.AverageDay, usd(164)},
+		{"average per month", report.AverageMonth, usd(5033)},
+	}
+	for _, tt := range totals {
+		if tt.got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+	if report.Count != 5 || report.From != "2026-08-01" || report.To != "2026-10-31" {
+		t.Errorf("report covers %d expenses from %s to %s", report.Count, report.From, report.To)
+	}
+	if share := report.Groups[0].Share; share < 39.7 || share > 39.8 {
+		t.Errorf("share of Travel = %.2f%%, want 39.74%%", share)
+	}
+
+	var top []int
+	for _, e := range report.Top {
+		top = append(top, e.ID)
+	}
+	if !slices.Equal(top, []int{3, 5}) || report.Top[0].InBase != usd(6000) {
+		t.Errorf("top = %v, want the two largest expenses 3 and 5", top)
+	}
+}
+
+func TestMonthOverMonth(t *testing.T) {
+	config := reportConfig()
+	report, err := config.buildReport(utcDate(2026, 7, 1), utcDate(2026, 12, 1), GroupByCategory, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change := func(pct float64) *float64 { return &pct }
+	want := []struct {
+		month  string
+		total  int64
+		change *float64
+	}{
+		{"2026-07", 9999, nil},
+		{"2026-08", 9100, change(-8.99)},
+		{"2026-09", 0, change(-100)},
+		{"2026-10", 6000, nil},
+		{"2026-11", 9999, change(66.65)},
+	}
+	if len(report.MonthOverMonth) != len(want) {
+		t.Fatalf("month over month = %+v, want %d months", report.MonthOverMonth, len(want))
+	}
+	for i, m := range report.MonthOverMonth {
+		w := want[i]
+		if m.Month != w.month || m.Total != usd(w.total) {
+			t.Errorf("month %d = %s %s, want %s %s", i, m.Month, m.Total, w.month, usd(w.total))
+		}
+		switch {
+		case (m.Change == nil) != (w.change == nil):
+			t.Errorf("%s change = %v, want %v", m.Month, m.Change, w.change)
+		case m.Change != nil && (*m.Change < *w.change-0.01 || *m.Change > *w.change+0.01):
+			t.Errorf("%s change = %.2f%%, want %.2f%%", m.Month, *m.Change, *w.change)
+		}
+	}
+	if len(report.Top) != 0 {
+		t.Errorf("top 0 listed %d expenses", len(report.Top))
+	}
+}
+
+func TestReportRange(t *testing.T) {
+	inZone(t, time.UTC)
+	expenses := reportConfig().Expenses
+
+	tests := []struct {
+		from, to  string
+		year      int
+		wantFrom  string
+		wantUntil string
+		wantErr   bool
+	}{
+		{"", "", 0, "2026-07-30", "2026-11-02", false},
+		{"2026-08-01", "2026-08-31", 0, "2026-08-01", "2026-09-01", false},
+		{"", "", 2025, "2025-01-01", "2026-01-01", false},
+		{"2026-08-01", "", 2026, "", "", true},
+		{"2026-09-01", "2026-08-01", 0, "", "", true},
+		{"01/08/2026", "", 0, "", "", true},
+	}
+	for _, tt := range tests {
+		from, until, err := reportRange(expenses, tt.from, tt.to, tt.year)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("reportRange(%q, %q, %d) succeeded, want an error", tt.from, tt.to, tt.year)
+			}
+			continue
+		}
+		if err != nil || from.Format(time.DateOnly) != tt.wantFrom || until.Format(time.DateOnly) != tt.wantUntil {
+			t.Errorf("reportRange(%q, %q, %d) = %s, %s, %v; want %s, %s", tt.from, tt.to, tt.year,
+				from.Format(time.DateOnly), until.Format(time.DateOnly), err, tt.wantFrom, tt.wantUntil)
+		}
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	for _, value := range []string{"category", "Month", "WEEK", "day"} {
+		if _, err := parseGroupBy(value); err != nil {
+			t.Errorf("parseGroupBy(%q): %v", value, err)
+		}
+	}
+	if _, err := parseGroupBy("year"); err == nil {
+		t.Error("parseGroupBy(\"year\") succeeded, want an error")
+	}
+}
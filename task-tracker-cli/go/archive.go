@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+
+	"task-cli/tasks"
+)
+
+// openArchive returns the store holding archived tasks: a JSON file next
+// to the task list, such as .tasks.archive.json, whatever backend the list
+// itself uses. Archived tasks keep their IDs.
+func openArchive(location storeLocation) *tasks.JSONStore {
+	return tasks.NewJSONStore(siblingPath(location.path(), ".archive.json"))
+}
+
+// archiveTasks moves the finished tasks matching filter that were last
+// changed more than days ago out of the list and into the archive. Undoing
+// the change brings the tasks back to the list; the archived copies are
+// replaced the next time they are archived.
+func archiveTasks(store tasks.Store, archive *tasks.JSONStore, filter tasks.Filter, days int) error {
+	archived, err := tracker(store).Archive(archive, filter, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return err
+	}
+	if len(archived) == 0 {
+		out.info("No finished tasks older than %d day(s) to archive", days)
+		out.changed(nil)
+		return nil
+	}
+	out.info("%d task(s) archived to %s", len(archived), archive.Path())
+	out.changed(archived)
+	return nil
+}
+
+// restoreTask moves archived task id back into the list.
+func restoreTask(store tasks.Store, archive *tasks.JSONStore, id int) error {
+	task, err := tracker(store).Restore(archive, id)
+	if err != nil {
+		return err
+	}
+	if task.ID != id {
+		out.info("Task %d restored as task %d (its ID is in use)", id, task.ID)
+	} else {
+		out.info("Task %d restored", id)
+	}
+	out.changed([]tasks.Task{task})
+	return nil
+}
+
+// purgeArchive deletes the archived tasks last changed before the given
+// day for good. The journal does not cover the archive, so this cannot be
+// undone.
+func purgeArchive(archive *tasks.JSONStore, before time.Time) error {
+	all, err := archive.List(tasks.Filter{})
+	if err != nil {
+		return storageError(err)
+	}
+	var ids []int
+	var purged []tasks.Task
+	for _, task := range all {
+		if task.UpdatedAt.Before(before) {
+			ids = append(ids, task.ID)
+			purged = append(purged, task)
+		}
+	}
+	if len(ids) == 0 {
+		out.info("No archived tasks before %s", before.Format(tasks.DateLayout))
+		out.changed(nil)
+		return nil
+	}
+	if err := archive.Delete(ids...); err != nil {
+		return storageError(err)
+	}
+	out.info("%d archived task(s) purged", len(purged))
+	out.changed(purged)
+	return nil
+}
@@ -0,0 +1,857 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-cli/tasks"
+)
+
+// command is one node of the command tree. setup defines the flags of the
+// command and returns the function running it, so that help and shell
+// completion can list the flags without running anything.
+type command struct {
+	name    string
+	args    string // positional arguments, as shown in help
+	summary string
+	// maxArgs is the number of positional arguments accepted, or -1 for
+	// any number.
+	maxArgs int
+	needs   requirement
+	setup   func(fs *flag.FlagSet) runFunc
+	// complete lists candidates for the positional arguments and
+	// flagValues for the values of flags, by flag name.
+	complete   completer
+	flagValues map[string]completer
+	// subcommands replace setup for commands such as note add.
+	subcommands []*command
+}
+
+type runFunc func(env *commandEnv, args []string) error
+
+// requirement is how much of the task list a command needs before it
+// runs.
+type requirement int
+
+const (
+	// needsStore opens the task list.
+	needsStore requirement = iota
+	// needsLocation finds the task list and loads its workflow without
+	// opening it.
+	needsLocation
+	// needsNothing runs before any task list is looked for.
+	needsNothing
+)
+
+// commandEnv is what a command runs against.
+type commandEnv struct {
+	flags    globalFlags
+	location storeLocation
+	store    *journalStore
+}
+
+func (c *command) flagSet() (*flag.FlagSet, runFunc) {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	fs.SetOutput(os.Stdout)
+	var run runFunc
+	if c.setup != nil {
+		run = c.setup(fs)
+	}
+	fs.Usage = func() { printCommandHelp(os.Stdout, c) }
+	return fs, run
+}
+
+// find returns the subcommand named name.
+func (c *command) find(name string) *command {
+	for _, sub := range c.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// rootCommand is the top of the command tree. It is built on demand since
+// help refers back to it.
+func rootCommand() *command {
+	return &command{name: "task-cli", subcommands: []*command{
+		{
+			name: "add", args: "description", summary: "Add a new task", maxArgs: 1,
+			flagValues: map[string]completer{
+				"priority": words("low", "medium", "high"), "due": dateWords, "project": projects,
+				"tag": tags, "parent": taskIDs, "blocked-by": taskIDs,
+				"repeat": words("daily", "weekly", "monthly", "every:"),
+			},
+			setup: func(fs *flag.FlagSet) runFunc {
+				priority := fs.String("priority", "", "Task priority (low, medium, high)")
+				due := fs.String("due", "", "Due date (YYYY-MM-DD, today, tomorrow, fri, next fri, in 3 days)")
+				project := fs.String("project", "", "Project the task belongs to")
+				var tags stringList
+				fs.Var(&tags, "tag", "Tag to attach (repeatable or comma separated)")
+				parent := fs.Int("parent", 0, "ID of the parent task")
+				var blockers intList
+				fs.Var(&blockers, "blocked-by", "ID of a task that must be done first (repeatable or comma separated)")
+				repeat := fs.String("repeat", "", "Recurrence rule (daily, weekly[:mon,thu], monthly[:15], every:<days>d)")
+				return func(env *commandEnv, args []string) error {
+					if len(args) < 1 {
+						return invalidInput("task description required")
+					}
+					task := tasks.Task{Description: args[0], Project: *project, ParentID: *parent}
+					task.AddTags(tags)
+					task.AddBlockers(blockers)
+					if *priority != "" {
+						p, err := tasks.ParsePriority(*priority)
+						if err != nil {
+							return invalidInput("%w", err)
+						}
+						task.Priority = p
+					}
+					if *due != "" {
+						dueDate, err := parseDueDate(*due)
+						if err != nil {
+							return invalidInput("%w", err)
+						}
+						task.DueDate = &dueDate
+					}
+					if *repeat != "" {
+						recurrence, err := tasks.ParseRecurrence(*repeat, task.DueDate)
+						if err != nil {
+							return invalidInput("%w", err)
+						}
+						task.Recurrence = recurrence
+					}
+					return addTask(env.store, task)
+				}
+			},
+		},
+		{
+			name: "list", args: "[filter]", summary: "List tasks (e.g. done, or status:todo tag:api -tag:blocked)", maxArgs: -1,
+			complete:   filterTerms,
+			flagValues: map[string]completer{"sort": words("priority", "due")},
+			setup: func(fs *flag.FlagSet) runFunc {
+				sortBy := fs.String("sort", "", "Sort order (priority, due)")
+				tree := fs.Bool("tree", false, "Show subtasks indented below their parent")
+				archived := fs.Bool("archived", false, "List archived tasks instead")
+				return func(env *commandEnv, args []string) error {
+					if *sortBy != "" && *sortBy != "priority" && *sortBy != "due" {
+						return invalidInput("invalid sort order (use priority or due)")
+					}
+					filter, err := tasks.ParseFilter(args, workflow)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					if *archived {
+						return listTasks(openArchive(env.location), filter, *sortBy, *tree)
+					}
+					return listTasks(env.store, filter, *sortBy, *tree)
+				}
+			},
+		},
+		{
+			name: "update", args: "id [description]", summary: "Update a task description or other fields", maxArgs: 2,
+			complete: taskIDs,
+			flagValues: map[string]completer{
+				"priority": words("low", "medium", "high", "none"), "due": withNone(dateWords),
+				"project": withNone(projects), "tag": tags, "untag": tags, "parent": withNone(taskIDs),
+				"blocked-by": taskIDs, "unblock": taskIDs,
+				"repeat": words("daily", "weekly", "monthly", "every:", "none"),
+			},
+			setup: func(fs *flag.FlagSet) runFunc {
+				priority := fs.String("priority", "", "New priority (low, medium, high, none)")
+				due := fs.String("due", "", "New due date (YYYY-MM-DD or a phrase such as tomorrow, none to clear)")
+				project := fs.String("project", "", "New project (none to clear)")
+				var addTags, removeTags stringList
+				fs.Var(&addTags, "tag", "Tag to add (repeatable or comma separated)")
+				fs.Var(&removeTags, "untag", "Tag to remove (repeatable or comma separated)")
+				parent := fs.String("parent", "", "New parent task ID (none to clear)")
+				var addBlockers, unblock intList
+				fs.Var(&addBlockers, "blocked-by", "Add a blocking task ID (repeatable or comma separated)")
+				fs.Var(&unblock, "unblock", "Remove a blocking task ID (repeatable or comma separated)")
+				repeat := fs.String("repeat", "", "New recurrence rule (none to stop repeating)")
+				return func(env *commandEnv, args []string) error {
+					if len(args) < 1 {
+						return invalidInput("task ID required")
+					}
+					id, err := parseID(args[0])
+					if err != nil {
+						return err
+					}
+
+					var update tasks.Update
+					if len(args) > 1 {
+						update.Description = &args[1]
+					}
+					if *priority != "" {
+						p, err := tasks.ParsePriority(*priority)
+						if err != nil {
+							return invalidInput("%w", err)
+						}
+						update.Priority = &p
+					}
+					switch *due {
+					case "":
+					case "none":
+						update.ClearDue = true
+					default:
+						dueDate, err := parseDueDate(*due)
+						if err != nil {
+							return invalidInput("%w", err)
+						}
+						update.DueDate = &dueDate
+					}
+					switch *project {
+					case "":
+					case "none":
+						update.Project = new(string)
+					default:
+						update.Project = project
+					}
+					update.AddTags = addTags
+					update.RemoveTags = removeTags
+					switch *parent {
+					case "":
+					case "none":
+						update.ParentID = new(int)
+					default:
+						parentID, err := strconv.Atoi(*parent)
+						if err != nil {
+							return invalidInput("invalid parent ID %q", *parent)
+						}
+						update.ParentID = &parentID
+					}
+					update.AddBlockers = addBlockers
+					update.Unblock = unblock
+					switch *repeat {
+					case "":
+					case "none":
+						update.NoRepeat = true
+					default:
+						recurrence, err := tasks.ParseRecurrence(*repeat, update.DueDate)
+						if err != nil {
+							return invalidInput("%w", err)
+						}
+						update.Recurrence = recurrence
+					}
+					if update.Empty() {
+						return invalidInput("new description or a flag to change required")
+					}
+					return updateTask(env.store, id, update)
+				}
+			},
+		},
+		{
+			name: "delete", args: "id|filter", summary: "Delete a task or every task matching a filter", maxArgs: -1,
+			complete: selector,
+			setup: func(fs *flag.FlagSet) runFunc {
+				cascade := fs.Bool("cascade", false, "Also delete all subtasks")
+				return func(env *commandEnv, args []string) error {
+					if len(args) < 1 {
+						return invalidInput("task ID or filter required")
+					}
+					id, filter, err := parseSelector(args)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					if id == 0 {
+						return deleteMatchingTasks(env.store, filter, *cascade)
+					}
+					return deleteTask(env.store, id, *cascade)
+				}
+			},
+		},
+		{
+			name: "move", args: "id|filter status", summary: "Move tasks to another status, as far as the workflow allows", maxArgs: -1,
+			complete: moveTargets,
+			setup: moveCommand(func(args []string) (tasks.StatusTask, []string, error) {
+				if len(args) < 2 {
+					return "", nil, invalidInput("task ID or filter and a status required")
+				}
+				return tasks.StatusTask(args[len(args)-1]), args[:len(args)-1], nil
+			}),
+		},
+		{
+			name: "mark-in-progress", args: "id|filter", summary: "Same as move ... in-progress", maxArgs: -1,
+			complete: selector,
+			setup: moveCommand(func(args []string) (tasks.StatusTask, []string, error) {
+				return tasks.StatusTaskInProgress, args, nil
+			}),
+		},
+		{
+			name: "mark-done", args: "id|filter", summary: "Same as move ... done (refused while blockers are open)", maxArgs: -1,
+			complete: selector,
+			setup: moveCommand(func(args []string) (tasks.StatusTask, []string, error) {
+				return workflow.DoneStatus(), args, nil
+			}),
+		},
+		{
+			name: "note", summary: "Manage task notes",
+			subcommands: []*command{{
+				name: "add", args: "id [text]", summary: "Add a note to a task (opens $EDITOR without text)", maxArgs: -1,
+				complete: taskIDs,
+				setup: func(fs *flag.FlagSet) runFunc {
+					return func(env *commandEnv, args []string) error {
+						if len(args) < 1 {
+							return invalidInput("task ID required")
+						}
+						id, err := parseID(args[0])
+						if err != nil {
+							return err
+						}
+						return addNote(env.store, id, strings.Join(args[1:], " "))
+					}
+				},
+			}},
+		},
+		{
+			name: "show", args: "id", summary: "Show a task with its notes and status log", maxArgs: 1,
+			complete: taskIDs,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					if len(args) < 1 {
+						return invalidInput("task ID required")
+					}
+					id, err := parseID(args[0])
+					if err != nil {
+						return err
+					}
+					return showTask(env.store, id)
+				}
+			},
+		},
+		{
+			name: "due", args: "[days]", summary: "List unfinished tasks due within days (default 7)", maxArgs: 1,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					days := 7
+					if len(args) > 0 {
+						n, err := strconv.Atoi(args[0])
+						if err != nil || n < 0 {
+							return invalidInput("invalid number of days %q", args[0])
+						}
+						days = n
+					}
+					return listDueTasks(env.store, days)
+				}
+			},
+		},
+		{
+			name: "overdue", summary: "List unfinished tasks past their due date",
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					return listOverdueTasks(env.store)
+				}
+			},
+		},
+		{
+			name: "search", args: "query [filter]", summary: "Fuzzy search descriptions, tags, projects and notes", maxArgs: -1,
+			complete: filterTerms,
+			setup: func(fs *flag.FlagSet) runFunc {
+				limit := fs.Int("limit", 20, "Show at most this many matches (0 for all)")
+				return func(env *commandEnv, args []string) error {
+					query, filterArgs := splitSearchArgs(args)
+					if query == "" {
+						return invalidInput("search query required")
+					}
+					filter, err := tasks.ParseFilter(filterArgs, workflow)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					return searchTasks(env.store, env.location, query, filter, *limit)
+				}
+			},
+		},
+		{
+			name: "start", args: "id", summary: "Start the work timer on a task (one at a time)", maxArgs: 1,
+			complete: taskIDs,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					if len(args) < 1 {
+						return invalidInput("task ID required")
+					}
+					id, err := parseID(args[0])
+					if err != nil {
+						return err
+					}
+					return startTimer(env.store, id)
+				}
+			},
+		},
+		{
+			name: "stop", args: "[id]", summary: "Stop the running work timer", maxArgs: 1,
+			complete: taskIDs,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					id := 0
+					if len(args) > 0 {
+						var err error
+						if id, err = parseID(args[0]); err != nil {
+							return err
+						}
+					}
+					return stopTimerCommand(env.store, id)
+				}
+			},
+		},
+		{
+			name: "report", summary: "Total tracked time",
+			flagValues: map[string]completer{"from": dateWords, "to": dateWords, "by": words("day", "tag")},
+			setup: func(fs *flag.FlagSet) runFunc {
+				fromFlag := fs.String("from", "", "First day of the report (default 7 days ago)")
+				toFlag := fs.String("to", "", "Last day of the report (default today)")
+				by := fs.String("by", "day", "Group by day or tag")
+				return func(env *commandEnv, args []string) error {
+					to := tasks.StartOfDay(time.Now())
+					from := to.AddDate(0, 0, -6)
+					var err error
+					if *fromFlag != "" {
+						if from, err = parseDueDate(*fromFlag); err != nil {
+							return invalidInput("%w", err)
+						}
+					}
+					if *toFlag != "" {
+						if to, err = parseDueDate(*toFlag); err != nil {
+							return invalidInput("%w", err)
+						}
+					}
+					if to.Before(from) {
+						return invalidInput("--to is before --from")
+					}
+					if *by != "day" && *by != "tag" {
+						return invalidInput("invalid grouping %q (use day or tag)", *by)
+					}
+					return showReport(env.store, from, to, *by)
+				}
+			},
+		},
+		{
+			name: "stats", args: "[filter]", summary: "Tasks created and completed per week, time to done, open tasks and a burndown chart", maxArgs: -1,
+			complete:   filterTerms,
+			flagValues: map[string]completer{"from": dateWords, "to": dateWords},
+			setup: func(fs *flag.FlagSet) runFunc {
+				fromFlag := fs.String("from", "", "First day of the period (default 4 weeks ago)")
+				toFlag := fs.String("to", "", "Last day of the period (default today)")
+				return func(env *commandEnv, args []string) error {
+					to := tasks.StartOfDay(time.Now())
+					from := to.AddDate(0, 0, -27)
+					var err error
+					if *fromFlag != "" {
+						if from, err = parseDueDate(*fromFlag); err != nil {
+							return invalidInput("%w", err)
+						}
+					}
+					if *toFlag != "" {
+						if to, err = parseDueDate(*toFlag); err != nil {
+							return invalidInput("%w", err)
+						}
+					}
+					if to.Before(from) {
+						return invalidInput("--to is before --from")
+					}
+					filter, err := tasks.ParseFilter(args, workflow)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					return showStats(env.store, openArchive(env.location), filter, from, to)
+				}
+			},
+		},
+		{
+			name: "archive", args: "[filter]", summary: "Move done tasks into the archive file next to the task list", maxArgs: -1,
+			complete: filterTerms,
+			setup: func(fs *flag.FlagSet) runFunc {
+				days := fs.Int("days", 30, "Archive done tasks last changed more than this many days ago")
+				return func(env *commandEnv, args []string) error {
+					if *days < 0 {
+						return invalidInput("invalid number of days %d", *days)
+					}
+					filter, err := tasks.ParseFilter(args, workflow)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					return archiveTasks(env.store, openArchive(env.location), filter, *days)
+				}
+			},
+		},
+		{
+			name: "restore", args: "id", summary: "Move an archived task back into the list", maxArgs: 1,
+			complete: archivedIDs,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					if len(args) < 1 {
+						return invalidInput("archived task ID required")
+					}
+					id, err := parseID(args[0])
+					if err != nil {
+						return err
+					}
+					return restoreTask(env.store, openArchive(env.location), id)
+				}
+			},
+		},
+		{
+			name: "purge", summary: "Delete archived tasks last changed before a day for good", needs: needsLocation,
+			flagValues: map[string]completer{"before": dateWords},
+			setup: func(fs *flag.FlagSet) runFunc {
+				before := fs.String("before", "", "Delete archived tasks last changed before this day (required)")
+				return func(env *commandEnv, args []string) error {
+					if *before == "" {
+						return invalidInput("--before date required")
+					}
+					day, err := parseDueDate(*before)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					return purgeArchive(openArchive(env.location), day)
+				}
+			},
+		},
+		{
+			name: "import", args: "file", summary: "Import a todo.txt, Markdown checklist or CSV file, skipping duplicates", maxArgs: 1,
+			complete:   files,
+			flagValues: map[string]completer{"format": words("todotxt", "markdown", "csv")},
+			setup: func(fs *flag.FlagSet) runFunc {
+				format := fs.String("format", "", "File format (todotxt, markdown, csv); default from the file extension")
+				return func(env *commandEnv, args []string) error {
+					if len(args) != 1 {
+						return invalidInput("file to import required (- reads stdin)")
+					}
+					fileFormat, err := parseFileFormat(*format, args[0])
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					return importTasks(env.store, args[0], fileFormat)
+				}
+			},
+		},
+		{
+			name: "export", args: "[filter]", summary: "Export tasks to stdout or --out", maxArgs: -1,
+			complete:   filterTerms,
+			flagValues: map[string]completer{"format": words("todotxt", "markdown", "csv"), "out": files},
+			setup: func(fs *flag.FlagSet) runFunc {
+				format := fs.String("format", "", "File format (todotxt, markdown, csv); default from the --out extension")
+				// Not --file, which names the task list for every command.
+				file := fs.String("out", "", "Write to this file instead of stdout")
+				return func(env *commandEnv, args []string) error {
+					if *format == "" && *file == "" {
+						return invalidInput("--format or --out required")
+					}
+					fileFormat, err := parseFileFormat(*format, *file)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					filter, err := tasks.ParseFilter(args, workflow)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					return exportTasks(env.store, filter, fileFormat, *file)
+				}
+			},
+		},
+		{
+			name: "history", args: "[id]", summary: "Show the change journal, optionally for one task", maxArgs: 1,
+			complete: taskIDs,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					id := 0
+					if len(args) > 0 {
+						var err error
+						if id, err = parseID(args[0]); err != nil {
+							return err
+						}
+					}
+					return showHistory(env.store, id)
+				}
+			},
+		},
+		{
+			name: "undo", summary: "Revert the last change",
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error { return undoLastChange(env.store) }
+			},
+		},
+		{
+			name: "redo", summary: "Re-apply the last undone change",
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error { return redoLastChange(env.store) }
+			},
+		},
+		{
+			name: "recover", args: "[n]", summary: "Restore the task file from backup n (default 1, newest)", maxArgs: 1,
+			complete: words("1", "2", "3"),
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					n := 1
+					if len(args) > 0 {
+						var err error
+						if n, err = strconv.Atoi(args[0]); err != nil {
+							return invalidInput("invalid backup number %q", args[0])
+						}
+					}
+					jsonStore, ok := env.store.Store.(*tasks.JSONStore)
+					if !ok {
+						return invalidInput("recover only applies to the JSON task file")
+					}
+					return recoverTasks(jsonStore, n)
+				}
+			},
+		},
+		{
+			name: "init", summary: "Start a task list (.tasks.json) for this directory tree", needs: needsNothing,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error { return initProject() }
+			},
+		},
+		{
+			name: "where", summary: "Show which task file commands use", needs: needsLocation,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					fmt.Println(env.location.path())
+					return nil
+				}
+			},
+		},
+		{
+			name: "workflow", summary: "Show the statuses and allowed transitions (from .tasks.workflow.json)", needs: needsLocation,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error { return showWorkflow(env.location) }
+			},
+		},
+		{
+			name: "migrate", summary: "Move all tasks to another storage backend", needs: needsLocation,
+			flagValues: map[string]completer{"to": words("sqlite", "json")},
+			setup: func(fs *flag.FlagSet) runFunc {
+				to := fs.String("to", "", "Target storage backend (sqlite, json)")
+				return func(env *commandEnv, args []string) error {
+					if *to == "" {
+						return invalidInput("--to sqlite or --to json required")
+					}
+					return migrateStore(env.location, *to)
+				}
+			},
+		},
+		{
+			name: "sync", summary: "Merge the tasks with a git repository and its remote, task by task",
+			flagValues: map[string]completer{"repo": files, "remote": files},
+			setup: func(fs *flag.FlagSet) runFunc {
+				repo := fs.String("repo", "", "Local git repository to commit the tasks to (remembered)")
+				remote := fs.String("remote", "", "Repository to pull from and push to (remembered)")
+				return func(env *commandEnv, args []string) error {
+					config, err := loadSyncConfig(env.location, *repo, *remote)
+					if err != nil {
+						return storageError(err)
+					}
+					return syncTasks(env.store, config)
+				}
+			},
+		},
+		{
+			name: "serve", summary: "Serve the tasks as a JSON API (/tasks, /tasks/{id}, /tasks/{id}/status)",
+			setup: func(fs *flag.FlagSet) runFunc {
+				addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+				return func(env *commandEnv, args []string) error { return serveTasks(env.store, *addr) }
+			},
+		},
+		{
+			name: "daemon", summary: "Send reminders before due dates through the log, a command or a webhook",
+			flagValues: map[string]completer{"before": words("1d", "1h", "1w,1d,0"), "log": files},
+			setup: func(fs *flag.FlagSet) runFunc {
+				before := fs.String("before", "1d", "Remind this long before the end of the due day (comma separated; 0 when overdue)")
+				every := fs.Duration("every", time.Minute, "How often to check the task list")
+				logFile := fs.String("log", "", "Append reminders to this file instead of printing them")
+				var commands, webhooks stringList
+				fs.Var(&commands, "exec", "Shell command to run per reminder, with TASK_MESSAGE and other TASK_* variables (repeatable)")
+				fs.Var(&webhooks, "webhook", "URL to post each reminder to as JSON (repeatable)")
+				once := fs.Bool("once", false, "Send the reminders due now and exit")
+				return func(env *commandEnv, args []string) error {
+					offsets, err := parseOffsets(*before)
+					if err != nil {
+						return invalidInput("%w", err)
+					}
+					if *every <= 0 {
+						return invalidInput("invalid interval %s", *every)
+					}
+					notifiers := []notifier{logNotifier{os.Stdout}}
+					if *logFile != "" {
+						file, err := os.OpenFile(*logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+						if err != nil {
+							return storageError(err)
+						}
+						defer file.Close()
+						notifiers[0] = logNotifier{file}
+					}
+					for _, command := range commands {
+						notifiers = append(notifiers, commandNotifier{command})
+					}
+					for _, url := range webhooks {
+						notifiers = append(notifiers, webhookNotifier{url, &http.Client{Timeout: 10 * time.Second}})
+					}
+					return runDaemon(env.store, env.location, offsets, notifiers, *every, *once)
+				}
+			},
+		},
+		{
+			name: "tui", summary: "Interactive board with a column per status",
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error { return runTUI(env.store) }
+			},
+		},
+		{
+			name: "completion", args: "bash|zsh|fish", summary: "Print the shell completion script", maxArgs: 1, needs: needsNothing,
+			complete: words("bash", "zsh", "fish"),
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					if len(args) != 1 {
+						return invalidInput("shell required (bash, zsh or fish)")
+					}
+					return printCompletionScript(args[0])
+				}
+			},
+		},
+		{
+			name: "help", args: "[command]", summary: "Show help for all commands or one of them", maxArgs: 2, needs: needsNothing,
+			complete: commandNames,
+			setup: func(fs *flag.FlagSet) runFunc {
+				return func(env *commandEnv, args []string) error {
+					cmd := rootCommand()
+					for _, name := range args {
+						if cmd = cmd.find(name); cmd == nil {
+							return invalidInput("unknown command %q", strings.Join(args, " "))
+						}
+					}
+					if len(args) == 0 {
+						printUsage(os.Stdout)
+					} else {
+						printCommandHelp(os.Stdout, cmd)
+					}
+					return nil
+				}
+			},
+		},
+	}}
+}
+
+// moveCommand sets up move and its mark-* aliases. target picks the status
+// and the selector out of the positional arguments.
+func moveCommand(target func(args []string) (tasks.StatusTask, []string, error)) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		force := fs.Bool("force", false, "Finish the task even while blocking tasks are open")
+		return func(env *commandEnv, args []string) error {
+			status, args, err := target(args)
+			if err != nil {
+				return err
+			}
+			if len(args) < 1 {
+				return invalidInput("task ID or filter required")
+			}
+			id, filter, err := parseSelector(args)
+			if err != nil {
+				return invalidInput("%w", err)
+			}
+			if id == 0 {
+				return updateMatchingStatus(env.store, filter, status, *force)
+			}
+			return updateStatus(env.store, id, status, *force)
+		}
+	}
+}
+
+// run finds the command named by args in the tree, prepares what it needs
+// and runs it.
+func run(flags globalFlags, args []string) error {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return nil
+	}
+	cmd := rootCommand()
+	var path []string
+	for len(cmd.subcommands) > 0 {
+		if len(args) == 0 {
+			printCommandHelp(os.Stderr, cmd)
+			return invalidInput("%s needs a subcommand", strings.Join(path, " "))
+		}
+		next := cmd.find(args[0])
+		if next == nil {
+			if len(path) == 0 {
+				printUsage(os.Stderr)
+			} else {
+				printCommandHelp(os.Stderr, cmd)
+			}
+			return invalidInput("unknown command %q", strings.Join(append(path, args[0]), " "))
+		}
+		cmd, path, args = next, append(path, args[0]), args[1:]
+	}
+
+	fs, runCmd := cmd.flagSet()
+	args = parseFlags(fs, args)
+	if cmd.maxArgs >= 0 && len(args) > cmd.maxArgs {
+		return invalidInput("too many arguments for %s (usage: task-cli %s %s)", strings.Join(path, " "), strings.Join(path, " "), cmd.args)
+	}
+
+	env := &commandEnv{flags: flags}
+	if cmd.needs == needsNothing {
+		return runCmd(env, args)
+	}
+	var err error
+	if env.location, err = findStore(flags.global, flags.file); err != nil {
+		return storageError(err)
+	}
+	if workflow, err = loadWorkflow(env.location); err != nil {
+		return invalidInput("%w", err)
+	}
+	if cmd.needs == needsLocation {
+		return runCmd(env, args)
+	}
+	if env.store, err = openStore(env.location); err != nil {
+		return storageError(fmt.Errorf("opening task store: %w", err))
+	}
+	defer env.store.Close()
+	return runCmd(env, args)
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: task-cli [command] [arguments]")
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range rootCommand().subcommands {
+		if len(cmd.subcommands) > 0 {
+			for _, sub := range cmd.subcommands {
+				fmt.Fprintf(w, "  %-30s	%s\n", cmd.name+" "+sub.name+" "+sub.args, sub.summary)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "  %-30s	%s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+	fmt.Fprintln(w, "Global flags:")
+	fmt.Fprintln(w, "  --output table|csv|json      	Output format for every command (default table)")
+	fmt.Fprintln(w, "  --quiet                      	Print nothing on success; add prints only the new ID")
+	fmt.Fprintln(w, "  --global                     	Use the global list instead of the nearest .tasks.json")
+	fmt.Fprintln(w, "  --file path                  	Use this task file (.json, or .db for SQLite)")
+	fmt.Fprintln(w, "Exit codes: 1 refused, 2 invalid input, 3 task not found, 4 storage failure")
+	fmt.Fprintln(w, "Filters: status:, tag:, project:, priority:, text: terms; prefix with - to exclude")
+	fmt.Fprintln(w, "Dates: YYYY-MM-DD, today, tomorrow, fri, next fri, in 3 days, in 2 weeks, next month")
+	fmt.Fprintln(w, "Run task-cli help <command> for its flags; task-cli completion bash|zsh|fish sets up completion.")
+}
+
+func printCommandHelp(w io.Writer, cmd *command) {
+	if len(cmd.subcommands) > 0 {
+		fmt.Fprintf(w, "Usage: task-cli %s <subcommand>\n\n%s\n\nSubcommands:\n", cmd.name, cmd.summary)
+		for _, sub := range cmd.subcommands {
+			fmt.Fprintf(w, "  %-20s	%s\n", strings.TrimSpace(sub.name+" "+sub.args), sub.summary)
+		}
+		return
+	}
+	fmt.Fprintf(w, "Usage: task-cli %s\n\n%s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	fs, _ := cmd.flagSet()
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"task-cli/tasks"
+)
+
+// completeCommand is the hidden command the completion scripts call with
+// the words typed so far, the last one being the word under the cursor. It
+// prints one candidate per line as value, a tab and a description.
+const completeCommand = "__complete"
+
+type candidate struct {
+	value       string
+	description string
+}
+
+// completer lists candidates for one argument or flag value. The shell
+// narrows them down to the word being typed.
+type completer func(c *completion) []candidate
+
+// completion is the state of the command line being completed.
+type completion struct {
+	flags globalFlags
+	// args are the positional arguments before the one being completed.
+	args []string
+	// current is the word being completed.
+	current string
+
+	loaded  bool
+	tasks   []tasks.Task
+	archive []tasks.Task
+}
+
+// globalFlagUsage describes the global flags for completion; file is the
+// only one whose value is completed by the shell.
+var globalFlagUsage = []candidate{
+	{"--output", "Output format (table, csv, json)"},
+	{"--quiet", "Print nothing on success"},
+	{"--global", "Use the global list"},
+	{"--file", "Use this task file"},
+}
+
+// completeWords prints the candidates for the last of words.
+func completeWords(w io.Writer, words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, before := words[len(words)-1], words[:len(words)-1]
+
+	// Global flags may appear anywhere; the value of one of them is
+	// completed here.
+	if len(before) > 0 {
+		switch before[len(before)-1] {
+		case "--output", "-output":
+			return printCandidates(w, current, outputFormats(nil))
+		case "--file", "-file":
+			return nil
+		}
+	}
+	flags, rest, err := parseGlobalFlags(before)
+	if err != nil {
+		return nil
+	}
+	c := &completion{flags: flags, current: current}
+
+	cmd := rootCommand()
+	for len(cmd.subcommands) > 0 && len(rest) > 0 {
+		next := cmd.find(rest[0])
+		if next == nil {
+			return nil
+		}
+		cmd, rest = next, rest[1:]
+	}
+	if len(cmd.subcommands) > 0 {
+		if strings.HasPrefix(current, "-") {
+			return printCandidates(w, current, globalFlagUsage)
+		}
+		return printCandidates(w, current, subcommandCandidates(cmd))
+	}
+
+	fs, _ := cmd.flagSet()
+	c.args, err = positionalArgs(fs, rest)
+	if err != nil {
+		// The last word is a flag waiting for its value.
+		name := strings.TrimLeft(rest[len(rest)-1], "-")
+		return printCandidates(w, current, c.flagValues(cmd, name))
+	}
+	if name, _, ok := strings.Cut(current, "="); ok && strings.HasPrefix(name, "-") {
+		flagName := strings.TrimLeft(name, "-")
+		values := c.flagValues(cmd, flagName)
+		if flagName == "output" {
+			values = outputFormats(c)
+		}
+		for i := range values {
+			values[i].value = name + "=" + values[i].value
+		}
+		return printCandidates(w, current, values)
+	}
+	if strings.HasPrefix(current, "--") || current == "-" {
+		return printCandidates(w, current, flagCandidates(fs))
+	}
+	if cmd.complete == nil || cmd.maxArgs >= 0 && len(c.args) >= cmd.maxArgs {
+		return nil
+	}
+	return printCandidates(w, current, cmd.complete(c))
+}
+
+// positionalArgs returns the positional arguments among args. It fails
+// when the last one is a flag still waiting for its value.
+func positionalArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil || hasValue || isBoolFlag(f) {
+			if f == nil {
+				// An excluding filter term such as -tag:blocked.
+				positional = append(positional, arg)
+			}
+			continue
+		}
+		if i == len(args)-1 {
+			return nil, fmt.Errorf("flag %s needs a value", arg)
+		}
+		i++
+	}
+	return positional, nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (c *completion) flagValues(cmd *command, name string) []candidate {
+	if complete := cmd.flagValues[name]; complete != nil {
+		return complete(c)
+	}
+	return nil
+}
+
+func flagCandidates(fs *flag.FlagSet) []candidate {
+	var candidates []candidate
+	fs.VisitAll(func(f *flag.Flag) {
+		candidates = append(candidates, candidate{"--" + f.Name, f.Usage})
+	})
+	return append(candidates, globalFlagUsage...)
+}
+
+func subcommandCandidates(cmd *command) []candidate {
+	var candidates []candidate
+	for _, sub := range cmd.subcommands {
+		candidates = append(candidates, candidate{sub.name, sub.summary})
+	}
+	return candidates
+}
+
+// printCandidates prints the candidates starting with current, in the given
+// order.
+func printCandidates(w io.Writer, current string, candidates []candidate) error {
+	seen := map[string]bool{}
+	for _, cand := range candidates {
+		if !strings.HasPrefix(cand.value, current) || seen[cand.value] {
+			continue
+		}
+		seen[cand.value] = true
+		description := strings.ReplaceAll(cand.description, "\t", " ")
+		fmt.Fprintf(w, "%s\t%s\n", cand.value, description)
+	}
+	return nil
+}
+
+// load reads the task list and its archive. Completion never changes or
+// creates anything and stays quiet about lists that cannot be read.
+func (c *completion) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	location, err := findStore(c.flags.global, c.flags.file)
+	if err != nil || !location.exists() {
+		return
+	}
+	if w, err := loadWorkflow(location); err == nil {
+		workflow = w
+	}
+	var store tasks.Store = tasks.NewJSONStore(location.jsonPath)
+	if _, err := os.Stat(location.dbPath); err == nil {
+		if store, err = tasks.OpenSQLiteStore(location.dbPath); err != nil {
+			return
+		}
+	}
+	defer store.Close()
+	c.tasks, _ = store.List(tasks.Filter{})
+	c.archive, _ = openArchive(location).List(tasks.Filter{})
+}
+
+func words(values ...string) completer {
+	return func(*completion) []candidate {
+		candidates := make([]candidate, len(values))
+		for i, value := range values {
+			candidates[i] = candidate{value: value}
+		}
+		return candidates
+	}
+}
+
+func union(completers ...completer) completer {
+	return func(c *completion) []candidate {
+		var candidates []candidate
+		for _, complete := range completers {
+			candidates = append(candidates, complete(c)...)
+		}
+		return candidates
+	}
+}
+
+// withNone adds the none value that clears a field.
+func withNone(complete completer) completer {
+	return func(c *completion) []candidate {
+		return append([]candidate{{"none", "Clear the field"}}, complete(c)...)
+	}
+}
+
+// files leaves completing file names to the shell, which falls back to
+// them when there are no candidates.
+func files(*completion) []candidate { return nil }
+
+func outputFormats(*completion) []candidate {
+	return words(string(outputTable), string(outputCSV), string(outputJSON))(nil)
+}
+
+// dateWords are the dates that fit in one word; parseDueDate also reads
+// phrases such as "next fri" and "in 3 days".
+func dateWords(*completion) []candidate {
+	return []candidate{
+		{"today", ""}, {"tomorrow", ""},
+		{"mon", ""}, {"tue", ""}, {"wed", ""}, {"thu", ""}, {"fri", ""}, {"sat", ""}, {"sun", ""},
+	}
+}
+
+// taskIDs lists unfinished tasks first, then finished ones.
+func taskIDs(c *completion) []candidate {
+	c.load()
+	tasks := slices.Clone(c.tasks)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if a, b := workflow.IsTerminal(tasks[i].Status), workflow.IsTerminal(tasks[j].Status); a != b {
+			return !a
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return idCandidates(tasks)
+}
+
+func archivedIDs(c *completion) []candidate {
+	c.load()
+	return idCandidates(c.archive)
+}
+
+func idCandidates(tasks []tasks.Task) []candidate {
+	candidates := make([]candidate, len(tasks))
+	for i, task := range tasks {
+		candidates[i] = candidate{strconv.Itoa(task.ID), fmt.Sprintf("[%s] %s", task.Status, task.Description)}
+	}
+	return candidates
+}
+
+func statuses(c *completion) []candidate {
+	c.load()
+	var candidates []candidate
+	for _, status := range workflow.Statuses {
+		description := "status"
+		if workflow.IsTerminal(status) {
+			description = "terminal status"
+		}
+		candidates = append(candidates, candidate{string(status), description})
+	}
+	return candidates
+}
+
+func tags(c *completion) []candidate {
+	c.load()
+	var values []string
+	for _, task := range c.tasks {
+		values = append(values, task.Tags...)
+	}
+	return sortedWords(values)
+}
+
+func projects(c *completion) []candidate {
+	c.load()
+	var values []string
+	for _, task := range c.tasks {
+		if task.Project != "" {
+			values = append(values, task.Project)
+		}
+	}
+	return sortedWords(values)
+}
+
+func sortedWords(values []string) []candidate {
+	sort.Strings(values)
+	return words(slices.Compact(values)...)(nil)
+}
+
+// filterTerms completes the field names of a filter and, after the colon,
+// the values known for the field. Excluding terms start with -.
+func filterTerms(c *completion) []candidate {
+	field, _, ok := strings.Cut(strings.TrimPrefix(c.current, "-"), ":")
+	exclude := ""
+	if strings.HasPrefix(c.current, "-") {
+		exclude = "-"
+	}
+	if !ok {
+		candidates := statuses(c)
+		for _, name := range tasks.FilterFields {
+			candidates = append(candidates, candidate{exclude + name + ":", "Filter on " + name})
+		}
+		if exclude != "" {
+			for i := range candidates[:len(workflow.Statuses)] {
+				candidates[i].value = exclude + candidates[i].value
+			}
+		}
+		return candidates
+	}
+
+	var values []candidate
+	switch field {
+	case "status":
+		values = statuses(c)
+	case "tag":
+		values = tags(c)
+	case "project":
+		values = projects(c)
+	case "priority":
+		values = words("low", "medium", "high")(c)
+	}
+	for i := range values {
+		values[i].value = exclude + field + ":" + values[i].value
+	}
+	return values
+}
+
+// selector completes the target of delete and move: a task ID or a filter.
+func selector(c *completion) []candidate {
+	if len(c.args) > 0 {
+		return filterTerms(c)
+	}
+	return union(taskIDs, filterTerms)(c)
+}
+
+// moveTargets completes move: after a task ID only the statuses of the
+// workflow, and after filter terms either another term or the status.
+func moveTargets(c *completion) []candidate {
+	if len(c.args) == 1 {
+		if _, err := strconv.Atoi(c.args[0]); err == nil {
+			return statuses(c)
+		}
+	}
+	if len(c.args) == 0 {
+		return selector(c)
+	}
+	return union(selector, statuses)(c)
+}
+
+// commandNames completes the command names help accepts.
+func commandNames(c *completion) []candidate {
+	cmd := rootCommand()
+	for _, name := range c.args {
+		if cmd = cmd.find(name); cmd == nil {
+			return nil
+		}
+	}
+	return subcommandCandidates(cmd)
+}
+
+// completionScripts hand the words on the command line to __complete. When
+// there are no candidates, the shell completes file names instead.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for task-cli; add to ~/.bashrc:
+#   source <(task-cli completion bash)
+_task_cli() {
+    local line=${COMP_LINE:0:COMP_POINT} words
+    read -ra words <<< "$line"
+    [[ $line == *[[:space:]] ]] && words+=("")
+    local cur=${words[${#words[@]}-1]} IFS=$'\n'
+    COMPREPLY=($("${words[0]}" __complete "${words[@]:1}" 2>/dev/null | cut -f1))
+    # Bash replaces only the part after a : or =, as in tag:api or --due=fri.
+    if [[ $cur == *[:=]* ]]; then
+        local prefix=${cur%"${cur##*[:=]}"}
+        COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+    fi
+    if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *: ]]; then
+        compopt -o nospace
+    fi
+}
+complete -o default -F _task_cli task-cli
+`,
+	"zsh": `#compdef task-cli
+# zsh completion for task-cli; add to ~/.zshrc:
+#   source <(task-cli completion zsh)
+_task_cli() {
+    local -a candidates
+    local line
+    for line in "${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -n $line ]] || continue
+        candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+    done
+    if (( ${#candidates} )); then
+        _describe -t values task-cli candidates
+    else
+        _files
+    fi
+}
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+    _task_cli "$@"
+else
+    compdef _task_cli task-cli
+fi
+`,
+	"fish": `# fish completion for task-cli; add to ~/.config/fish/config.fish:
+#   task-cli completion fish | source
+function __task_cli_complete
+    set -l words (commandline -opc)
+    set -l candidates ($words[1] __complete $words[2..-1] (commandline -ct) 2>/dev/null)
+    if test (count $candidates) -eq 0
+        __fish_complete_path (commandline -ct)
+    else
+        printf '%s\n' $candidates
+    end
+end
+complete -c task-cli -f -a '(__task_cli_complete)'
+`,
+}
+
+func printCompletionScript(shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return invalidInput("unknown shell %q (use bash, zsh or fish)", shell)
+	}
+	fmt.Print(script)
+	return nil
+}
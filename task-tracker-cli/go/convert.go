@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"task-cli/tasks"
+)
+
+// fileFormat is a format tasks can be imported from and exported to.
+type fileFormat string
+
+const (
+	formatTodoTxt  fileFormat = "todotxt"
+	formatMarkdown fileFormat = "markdown"
+	formatCSV      fileFormat = "csv"
+)
+
+// parseFileFormat reads --format, falling back to the extension of path.
+func parseFileFormat(value, path string) (fileFormat, error) {
+	if value == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".txt":
+			return formatTodoTxt, nil
+		case ".md", ".markdown":
+			return formatMarkdown, nil
+		case ".csv":
+			return formatCSV, nil
+		}
+		return "", fmt.Errorf("cannot tell the format of %q; use --format todotxt, markdown or csv", path)
+	}
+	switch format := fileFormat(value); format {
+	case formatTodoTxt, formatMarkdown, formatCSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid format %q (use todotxt, markdown or csv)", value)
+}
+
+// exportTasks writes the tasks matching filter to path, or to stdout when
+// path is empty.
+func exportTasks(store tasks.Store, filter tasks.Filter, format fileFormat, path string) error {
+	all, err := store.List(tasks.Filter{})
+	if err != nil {
+		return storageError(err)
+	}
+	var list []tasks.Task
+	for _, task := range all {
+		if filter.Match(task) {
+			list = append(list, task)
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case formatTodoTxt:
+		writeTodoTxt(&buf, list)
+	case formatMarkdown:
+		writeMarkdown(&buf, list)
+	case formatCSV:
+		rows := make([][]string, len(list))
+		for i, task := range list {
+			rows[i] = taskCSVRow(task)
+		}
+		w := csv.NewWriter(&buf)
+		w.Write(taskCSVHeader)
+		w.WriteAll(rows)
+	}
+
+	if path == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := tasks.WriteFileAtomic(path, buf.Bytes()); err != nil {
+		return storageError(err)
+	}
+	out.info("Exported %d task(s) to %s", len(list), path)
+	out.changed(list)
+	return nil
+}
+
+// importTasks adds the tasks in path to the store. A task whose description
+// matches an existing one, ignoring case and spacing, is not added again;
+// its status, priority, due date and tags are brought in line with the file
+// instead, so importing the same file twice changes nothing.
+func importTasks(store tasks.Store, path string, format fileFormat) error {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return invalidInput("%w", err)
+	}
+
+	var list []tasks.Task
+	switch format {
+	case formatTodoTxt:
+		list, err = readTodoTxt(data)
+	case formatMarkdown:
+		list, err = readMarkdown(data)
+	case formatCSV:
+		list, err = readTaskCSV(data)
+	}
+	if err != nil {
+		return invalidInput("%s: %w", path, err)
+	}
+
+	// The duplicate check and every change it leads to are one
+	// transaction, so two imports of the same file cannot both add it.
+	var created, updated []tasks.Task
+	err = store.Modify(func(all *[]tasks.Task) error {
+		existing := map[string]int{}
+		for i, task := range *all {
+			existing[dedupKey(task.Description)] = i
+		}
+
+		// Imported tasks use IDs local to the file until they are
+		// stored; refs maps one to the other for parent and blocker
+		// links.
+		refs := map[int]int{}
+		var fresh []tasks.Task
+		seen := map[string]int{}
+		aliases := map[int]int{}
+		for _, task := range list {
+			key := dedupKey(task.Description)
+			if ref, ok := seen[key]; ok {
+				aliases[task.ID] = ref
+				continue
+			}
+			seen[key] = task.ID
+			if i, ok := existing[key]; ok {
+				refs[task.ID] = (*all)[i].ID
+				if mergeImported(&(*all)[i], task) {
+					updated = append(updated, (*all)[i])
+				}
+				continue
+			}
+			fresh = append(fresh, task)
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+
+		// The new tasks are stored without links first, so the links can
+		// point at any of them.
+		first := len(*all)
+		for _, task := range fresh {
+			ref := task.ID
+			task.ID, task.UID, task.ParentID, task.BlockedBy = tasks.NextID(*all), tasks.NewUID(), 0, nil
+			refs[ref] = task.ID
+			*all = append(*all, task)
+		}
+		for alias, ref := range aliases {
+			refs[alias] = refs[ref]
+		}
+
+		index := tasks.NewIndex(*all)
+		for i, links := range fresh {
+			task := (*all)[first+i]
+			task.ParentID = refs[links.ParentID]
+			for _, ref := range links.BlockedBy {
+				if id, ok := refs[ref]; ok {
+					task.AddBlockers([]int{id})
+				}
+			}
+			if task.ParentID == 0 && len(task.BlockedBy) == 0 {
+				continue
+			}
+			if err := index.ValidateLinks(task); err != nil {
+				warn("Task %d imported without its links: %v", task.ID, err)
+				continue
+			}
+			index[task.ID] = task
+			(*all)[first+i] = task
+		}
+		created = slices.Clone((*all)[first:])
+		return nil
+	})
+	if err != nil {
+		return storageError(err)
+	}
+
+	out.info("Imported %d new task(s), updated %d, %d already up to date", len(created), len(updated), len(list)-len(created)-len(updated))
+	out.changed(append(created, updated...))
+	return nil
+}
+
+func dedupKey(description string) string {
+	return strings.ToLower(strings.Join(strings.Fields(description), " "))
+}
+
+// mergeImported copies the fields the file sets onto an existing task and
+// reports whether anything changed.
+func mergeImported(task *tasks.Task, imported tasks.Task) bool {
+	changed := false
+	// An unchecked box says the task is not done, which does not move it
+	// back from a later open status such as in-progress.
+	keepStarted := imported.Status == workflow.Initial && !workflow.IsTerminal(task.Status)
+	if imported.Status != task.Status && !keepStarted {
+		task.SetStatus(imported.Status, time.Now())
+		changed = true
+	}
+	if imported.Priority != "" && imported.Priority != task.Priority {
+		task.Priority = imported.Priority
+		changed = true
+	}
+	if imported.DueDate != nil && (task.DueDate == nil || !imported.DueDate.Equal(*task.DueDate)) {
+		task.DueDate = imported.DueDate
+		changed = true
+	}
+	if imported.Project != "" && imported.Project != task.Project {
+		task.Project = imported.Project
+		changed = true
+	}
+	for _, tag := range imported.Tags {
+		if !task.HasTag(tag) {
+			task.AddTags([]string{tag})
+			changed = true
+		}
+	}
+	if changed {
+		task.UpdatedAt = time.Now()
+	}
+	return changed
+}
+
+// newImportedTask is the starting point for a task read from a file.
+func newImportedTask(ref int) tasks.Task {
+	now := time.Now()
+	return tasks.Task{ID: ref, Status: workflow.Initial, CreatedAt: now, UpdatedAt: now}
+}
+
+// readFields moves the key:value fields and tags in words onto task and
+// returns the remaining words as the description. tagPrefix is "@" for
+// todo.txt contexts and "#" for Markdown.
+func readFields(task *tasks.Task, words []string, tagPrefix string) (string, error) {
+	var description []string
+	for _, word := range words {
+		if name, ok := strings.CutPrefix(word, tagPrefix); ok && isTagName(name) {
+			task.AddTags([]string{name})
+			continue
+		}
+		if name, ok := strings.CutPrefix(word, "+"); ok && tagPrefix == "@" && isTagName(name) {
+			// todo.txt projects: the first one is the project, any
+			// further ones become tags.
+			if task.Project == "" {
+				task.Project = name
+			} else {
+				task.AddTags([]string{name})
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			description = append(description, word)
+			continue
+		}
+		switch key {
+		case "due":
+			due, err := parseDate(value)
+			if err != nil {
+				return "", err
+			}
+			task.DueDate = &due
+		case "priority", "pri":
+			priority, err := parseImportedPriority(value)
+			if err != nil {
+				return "", err
+			}
+			task.Priority = priority
+		case "project":
+			task.Project = value
+		case "status":
+			if !isKnownStatus(value) {
+				return "", fmt.Errorf("invalid status %q", value)
+			}
+			task.Status = tasks.StatusTask(value)
+		case "repeat":
+			recurrence, err := tasks.ParseRecurrence(value, task.DueDate)
+			if err != nil {
+				return "", err
+			}
+			task.Recurrence = recurrence
+		case "created":
+			created, err := parseDate(value)
+			if err != nil {
+				return "", err
+			}
+			task.CreatedAt = created
+		default:
+			description = append(description, word)
+		}
+	}
+	if len(description) == 0 {
+		return "", fmt.Errorf("task without a description")
+	}
+	return strings.Join(description, " "), nil
+}
+
+func isTagName(name string) bool {
+	r := []rune(name)
+	return len(r) > 0 && unicode.IsLetter(r[0])
+}
+
+// parseImportedPriority accepts task-cli priorities and todo.txt letters,
+// where A is high, B medium and anything later low.
+func parseImportedPriority(value string) (tasks.Priority, error) {
+	if len(value) == 1 && value[0] >= 'A' && value[0] <= 'Z' {
+		return todoTxtPriority(value[0]), nil
+	}
+	return tasks.ParsePriority(value)
+}
+
+func todoTxtPriority(letter byte) tasks.Priority {
+	switch letter {
+	case 'A':
+		return tasks.PriorityHigh
+	case 'B':
+		return tasks.PriorityMedium
+	}
+	return tasks.PriorityLow
+}
+
+func todoTxtLetter(p tasks.Priority) string {
+	switch p {
+	case tasks.PriorityHigh:
+		return "A"
+	case tasks.PriorityMedium:
+		return "B"
+	case tasks.PriorityLow:
+		return "C"
+	}
+	return ""
+}
+
+// writeFields appends the fields a format has no syntax of its own for.
+func writeFields(words []string, task tasks.Task, withProject bool) []string {
+	if task.DueDate != nil {
+		words = append(words, "due:"+task.DueDate.Format(tasks.DateLayout))
+	}
+	if withProject && task.Project != "" {
+		words = append(words, "project:"+task.Project)
+	}
+	// The checkbox or x mark already says whether a task is open or done.
+	if task.Status != workflow.Initial && task.Status != workflow.DoneStatus() {
+		words = append(words, "status:"+string(task.Status))
+	}
+	if task.Recurrence != nil {
+		words = append(words, "repeat:"+task.Recurrence.String())
+	}
+	return words
+}
+
+// writeTodoTxt writes one line per task following the todo.txt format:
+// completion mark and date, priority, creation date, then the description
+// with +project, @tag and key:value extensions.
+func writeTodoTxt(w io.Writer, list []tasks.Task) {
+	for _, task := range list {
+		var words []string
+		letter := todoTxtLetter(task.Priority)
+		if workflow.IsTerminal(task.Status) {
+			words = append(words, "x", task.UpdatedAt.Format(tasks.DateLayout))
+		} else if letter != "" {
+			words = append(words, "("+letter+")")
+		}
+		words = append(words, task.CreatedAt.Format(tasks.DateLayout), task.Description)
+		if task.Project != "" {
+			words = append(words, "+"+strings.ReplaceAll(task.Project, " ", "_"))
+		}
+		for _, tag := range task.Tags {
+			words = append(words, "@"+tag)
+		}
+		words = writeFields(words, task, false)
+		if workflow.IsTerminal(task.Status) && letter != "" {
+			words = append(words, "pri:"+letter)
+		}
+		fmt.Fprintln(w, strings.Join(words, " "))
+	}
+}
+
+var todoTxtPriorityPattern = regexp.MustCompile(`^\(([A-Z])\)$`)
+
+func readTodoTxt(data []byte) ([]tasks.Task, error) {
+	var list []tasks.Task
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		task := newImportedTask(line)
+
+		if words[0] == "x" {
+			task.Status = workflow.DoneStatus()
+			words = words[1:]
+			// A completed task may carry its completion date followed by
+			// its creation date.
+			if len(words) > 1 {
+				if done, err := parseDate(words[0]); err == nil {
+					task.UpdatedAt = done
+					words = words[1:]
+				}
+			}
+		} else if m := todoTxtPriorityPattern.FindStringSubmatch(words[0]); m != nil {
+			task.Priority = todoTxtPriority(m[1][0])
+			words = words[1:]
+		}
+		if len(words) > 1 {
+			if created, err := parseDate(words[0]); err == nil {
+				task.CreatedAt = created
+				words = words[1:]
+			}
+		}
+
+		description, err := readFields(&task, words, "@")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		task.Description = description
+		list = append(list, task)
+	}
+	return list, scanner.Err()
+}
+
+// writeMarkdown writes a GitHub task list with subtasks nested under their
+// parent.
+func writeMarkdown(w io.Writer, tasks []tasks.Task) {
+	ordered, depth := treeOrder(tasks)
+	for _, task := range ordered {
+		box := "[ ]"
+		if workflow.IsTerminal(task.Status) {
+			box = "[x]"
+		}
+		words := []string{strings.Repeat("  ", depth[task.ID]) + "-", box, task.Description}
+		for _, tag := range task.Tags {
+			words = append(words, "#"+tag)
+		}
+		if task.Priority != "" {
+			words = append(words, "priority:"+string(task.Priority))
+		}
+		words = writeFields(words, task, true)
+		fmt.Fprintln(w, strings.Join(words, " "))
+	}
+}
+
+var checklistPattern = regexp.MustCompile(`^(\s*)[-*+] \[([ xX])\]\s+(.*)$`)
+
+// readMarkdown reads the checklist items of a Markdown file, skipping any
+// other lines. Items indented below another item become its subtasks.
+func readMarkdown(data []byte) ([]tasks.Task, error) {
+	type level struct{ indent, ref int }
+	var (
+		list  []tasks.Task
+		stack []level
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		m := checklistPattern.FindStringSubmatch(strings.ReplaceAll(scanner.Text(), "\t", "    "))
+		if m == nil {
+			continue
+		}
+		task := newImportedTask(line)
+		if m[2] != " " {
+			task.Status = workflow.DoneStatus()
+		}
+
+		indent := len(m[1])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			task.ParentID = stack[len(stack)-1].ref
+		}
+		stack = append(stack, level{indent, line})
+
+		description, err := readFields(&task, strings.Fields(m[3]), "#")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		task.Description = description
+		list = append(list, task)
+	}
+	return list, scanner.Err()
+}
+
+// readTaskCSV reads the columns written by export and --output csv. Only
+// description is required and the columns may come in any order.
+func readTaskCSV(data []byte) ([]tasks.Task, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	column := map[string]int{}
+	for i, name := range records[0] {
+		column[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := column["description"]; !ok {
+		return nil, fmt.Errorf("no description column")
+	}
+
+	var list []tasks.Task
+	for n, record := range records[1:] {
+		line := n + 2
+		field := func(name string) string {
+			if i, ok := column[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		fail := func(err error) ([]tasks.Task, error) {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		task := newImportedTask(-line)
+		if id, err := strconv.Atoi(field("id")); err == nil {
+			task.ID = id
+		}
+		if task.Description = field("description"); task.Description == "" {
+			return fail(fmt.Errorf("task without a description"))
+		}
+		if status := field("status"); status != "" {
+			if !isKnownStatus(status) {
+				return fail(fmt.Errorf("invalid status %q", status))
+			}
+			task.Status = tasks.StatusTask(status)
+		}
+		if value := field("priority"); value != "" {
+			if task.Priority, err = parseImportedPriority(value); err != nil {
+				return fail(err)
+			}
+		}
+		if value := field("due"); value != "" {
+			due, err := parseDate(value)
+			if err != nil {
+				return fail(err)
+			}
+			task.DueDate = &due
+		}
+		task.Project = field("project")
+		var tags stringList
+		tags.Set(field("tags"))
+		task.AddTags(tags)
+		if value := field("parent"); value != "" {
+			if task.ParentID, err = strconv.Atoi(value); err != nil {
+				return fail(fmt.Errorf("invalid parent %q", value))
+			}
+		}
+		var blockers intList
+		if err := blockers.Set(field("blocked_by")); err != nil {
+			return fail(err)
+		}
+		task.BlockedBy = blockers
+		if value := field("repeat"); value != "" {
+			if task.Recurrence, err = tasks.ParseRecurrence(value, task.DueDate); err != nil {
+				return fail(err)
+			}
+		}
+		for name, target := range map[string]*time.Time{"created": &task.CreatedAt, "updated": &task.UpdatedAt} {
+			if value := field(name); value != "" {
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					if t, err = parseDate(value); err != nil {
+						return fail(fmt.Errorf("invalid %s time %q", name, value))
+					}
+				}
+				*target = t
+			}
+		}
+		list = append(list, task)
+	}
+	return list, nil
+}
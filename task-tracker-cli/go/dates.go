@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-cli/tasks"
+)
+
+// weekdays maps day names and their usual abbreviations to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// parseDate reads a YYYY-MM-DD date in the local time zone. Imported files
+// use it, so a word such as "today" in a description stays a word.
+func parseDate(value string) (time.Time, error) {
+	day, err := time.ParseInLocation(tasks.DateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", value)
+	}
+	return day, nil
+}
+
+// parseDueDate reads a date given as YYYY-MM-DD or as a phrase relative to
+// today, in the local time zone.
+func parseDueDate(value string) (time.Time, error) {
+	if due, err := parseDate(value); err == nil {
+		return due, nil
+	}
+	if due, ok := parseDatePhrase(value, time.Now()); ok {
+		return due, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or a phrase such as tomorrow, fri, next fri or in 3 days)", value)
+}
+
+// parseDatePhrase reads today, tomorrow, yesterday, a weekday (the next one
+// after today, with or without "next"), next week, next month and
+// "in <n> days|weeks|months", relative to the day of now.
+func parseDatePhrase(value string, now time.Time) (time.Time, bool) {
+	today := tasks.StartOfDay(now)
+	words := strings.Fields(strings.ToLower(value))
+	switch len(words) {
+	case 1:
+		switch words[0] {
+		case "today":
+			return today, true
+		case "tomorrow":
+			return today.AddDate(0, 0, 1), true
+		case "yesterday":
+			return today.AddDate(0, 0, -1), true
+		}
+		if day, ok := weekdays[words[0]]; ok {
+			return nextWeekday(today, day), true
+		}
+	case 2:
+		if words[0] != "next" {
+			break
+		}
+		switch words[1] {
+		case "week":
+			return today.AddDate(0, 0, 7), true
+		case "month":
+			return tasks.AddMonths(today, 1), true
+		}
+		if day, ok := weekdays[words[1]]; ok {
+			return nextWeekday(today, day), true
+		}
+	case 3:
+		if words[0] != "in" {
+			break
+		}
+		n, err := strconv.Atoi(words[1])
+		if words[1] == "a" || words[1] == "an" {
+			n, err = 1, nil
+		}
+		if err != nil || n < 0 {
+			break
+		}
+		switch strings.TrimSuffix(words[2], "s") {
+		case "day":
+			return today.AddDate(0, 0, n), true
+		case "week":
+			return today.AddDate(0, 0, 7*n), true
+		case "month":
+			return tasks.AddMonths(today, n), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextWeekday returns the first day after today falling on day, so that
+// fri said on a Friday means a week later.
+func nextWeekday(today time.Time, day time.Weekday) time.Time {
+	days := (int(day)-int(today.Weekday())+6)%7 + 1
+	return today.AddDate(0, 0, days)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"task-cli/tasks"
+)
+
+// treeOrder arranges tasks so every subtask follows its parent and returns
+// the nesting depth of each one. Tasks whose parent is not in the list are
+// shown at the top level; siblings keep their order in list.
+func treeOrder(list []tasks.Task) ([]tasks.Task, map[int]int) {
+	listed := tasks.NewIndex(list)
+	children := map[int][]tasks.Task{}
+	var roots []tasks.Task
+	for _, task := range list {
+		if _, ok := listed[task.ParentID]; ok && task.ParentID != 0 {
+			children[task.ParentID] = append(children[task.ParentID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	ordered := make([]tasks.Task, 0, len(list))
+	depth := map[int]int{}
+	var walk func(task tasks.Task, level int)
+	walk = func(task tasks.Task, level int) {
+		ordered = append(ordered, task)
+		depth[task.ID] = level
+		for _, child := range children[task.ID] {
+			walk(child, level+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	return ordered, depth
+}
+
+// intList is a repeatable flag of task IDs that also accepts comma
+// separated values.
+type intList []int
+
+func (l *intList) String() string {
+	return tasks.JoinIDs(*l)
+}
+
+func (l *intList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.Atoi(item)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid task ID %q", item)
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
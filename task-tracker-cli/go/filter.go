@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+
+	"task-cli/tasks"
+)
+
+// isFilterTerm reports whether term reads as a filter, such as status:todo,
+// -tag:blocked or a bare status, rather than as free text.
+func isFilterTerm(term string) bool {
+	if isKnownStatus(term) {
+		return true
+	}
+	field, _, ok := strings.Cut(strings.TrimPrefix(term, "-"), ":")
+	return ok && tasks.IsFilterField(field) || strings.HasPrefix(term, "-") && len(term) > 1
+}
+
+// isKnownStatus reports whether value is a status of the current workflow.
+func isKnownStatus(value string) bool {
+	return workflow.Known(tasks.StatusTask(value))
+}
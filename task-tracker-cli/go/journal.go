@@ -0,0 +1,489 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"task-cli/tasks"
+)
+
+type journalOp string
+
+const (
+	journalAdd    journalOp = "add"
+	journalUpdate journalOp = "update"
+	journalStatus journalOp = "status"
+	journalDelete journalOp = "delete"
+	journalUndo   journalOp = "undo"
+	journalRedo   journalOp = "redo"
+)
+
+// journalEntry is one line of the append-only journal. Every mutation keeps
+// the task as it was before and after the change, which is all undo and
+// redo need. All entries written by one command share a batch, so a bulk
+// command is undone in one step.
+type journalEntry struct {
+	Batch  string      `json:"batch"`
+	Time   time.Time   `json:"time"`
+	Op     journalOp   `json:"op"`
+	TaskID int         `json:"taskId,omitempty"`
+	Before *tasks.Task `json:"before,omitempty"`
+	After  *tasks.Task `json:"after,omitempty"`
+	// Target is the batch reverted or replayed by an undo or redo entry.
+	Target string `json:"target,omitempty"`
+}
+
+// journalStore records every change made through it in a journal file next
+// to the task store.
+type journalStore struct {
+	tasks.Store
+	path  string
+	batch string
+}
+
+func newJournalStore(store tasks.Store, storePath string) *journalStore {
+	return &journalStore{
+		Store: store,
+		path:  siblingPath(storePath, ".journal"),
+		batch: newBatchID(),
+	}
+}
+
+func newBatchID() string {
+	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid())
+}
+
+// startBatch begins a new undo step. A command runs as a single batch;
+// the interactive board starts one for every change it makes.
+func (s *journalStore) startBatch() {
+	s.batch = newBatchID()
+}
+
+func (s *journalStore) Create(tasks ...tasks.Task) ([]tasks.Task, error) {
+	created, err := s.Store.Create(tasks...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]journalEntry, len(created))
+	for i := range created {
+		entries[i] = s.entry(journalAdd, created[i].ID, nil, &created[i])
+	}
+	return created, s.append(entries...)
+}
+
+// Update and Delete go through Modify, so the state journaled as before
+// is read in the same transaction that replaces it.
+func (s *journalStore) Update(updated ...tasks.Task) error {
+	return s.Modify(func(list *[]tasks.Task) error {
+		for _, task := range updated {
+			i := indexOf(*list, task.ID)
+			if i < 0 {
+				return tasks.ErrNotFound
+			}
+			(*list)[i] = task
+		}
+		return nil
+	})
+}
+
+func (s *journalStore) Delete(ids ...int) error {
+	return s.Modify(func(list *[]tasks.Task) error {
+		for _, id := range ids {
+			i := indexOf(*list, id)
+			if i < 0 {
+				return tasks.ErrNotFound
+			}
+			*list = slices.Delete(*list, i, i+1)
+		}
+		return nil
+	})
+}
+
+func indexOf(list []tasks.Task, id int) int {
+	return slices.IndexFunc(list, func(task tasks.Task) bool { return task.ID == id })
+}
+
+// Modify journals the tasks fn added, changed or dropped. The tasks are
+// compared as they were inside the transaction, so the journal records
+// exactly the change that was saved.
+func (s *journalStore) Modify(fn func(list *[]tasks.Task) error) error {
+	var entries []journalEntry
+	err := s.Store.Modify(func(list *[]tasks.Task) error {
+		// fn may change the tasks in place, so they are kept encoded.
+		before := make(map[int][]byte, len(*list))
+		order := make([]int, len(*list))
+		for i, task := range *list {
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			before[task.ID], order[i] = data, task.ID
+		}
+		if err := fn(list); err != nil {
+			return err
+		}
+		var err error
+		entries, err = s.diff(before, order, *list)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return s.append(entries...)
+}
+
+// diff turns the difference between the encoded tasks before a change and
+// the list after it into journal entries.
+func (s *journalStore) diff(before map[int][]byte, order []int, after []tasks.Task) ([]journalEntry, error) {
+	decode := func(id int) (*tasks.Task, error) {
+		var task tasks.Task
+		if err := json.Unmarshal(before[id], &task); err != nil {
+			return nil, err
+		}
+		return &task, nil
+	}
+
+	var entries []journalEntry
+	kept := make(map[int]bool, len(after))
+	for i := range after {
+		task := &after[i]
+		kept[task.ID] = true
+		old, existed := before[task.ID]
+		if !existed {
+			entries = append(entries, s.entry(journalAdd, task.ID, nil, task))
+			continue
+		}
+		data, err := json.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(old, data) {
+			continue
+		}
+		previous, err := decode(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		op := journalUpdate
+		if previous.Status != task.Status {
+			op = journalStatus
+		}
+		entries = append(entries, s.entry(op, task.ID, previous, task))
+	}
+	for _, id := range order {
+		if kept[id] {
+			continue
+		}
+		previous, err := decode(id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, s.entry(journalDelete, id, previous, nil))
+	}
+	return entries, nil
+}
+
+func (s *journalStore) entry(op journalOp, id int, before, after *tasks.Task) journalEntry {
+	return journalEntry{Batch: s.batch, Time: time.Now(), Op: op, TaskID: id, Before: before, After: after}
+}
+
+// append writes the entries with a single O_APPEND write so lines from
+// concurrent processes never interleave.
+func (s *journalStore) append(entries ...journalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return nil
+}
+
+func (s *journalStore) read() ([]journalEntry, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", s.path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// journalState replays the journal into the stack of batches that can be
+// undone and the stack of undone batches that can be redone.
+type journalState struct {
+	batches map[string][]journalEntry
+	done    []string
+	undone  []string
+}
+
+func replayJournal(entries []journalEntry) journalState {
+	state := journalState{batches: map[string][]journalEntry{}}
+	for _, entry := range entries {
+		switch entry.Op {
+		case journalUndo:
+			if n := len(state.done); n > 0 && state.done[n-1] == entry.Target {
+				state.done = state.done[:n-1]
+				state.undone = append(state.undone, entry.Target)
+			}
+		case journalRedo:
+			if n := len(state.undone); n > 0 && state.undone[n-1] == entry.Target {
+				state.undone = state.undone[:n-1]
+				state.done = append(state.done, entry.Target)
+			}
+		default:
+			if _, seen := state.batches[entry.Batch]; !seen {
+				state.done = append(state.done, entry.Batch)
+				state.undone = nil
+			}
+			state.batches[entry.Batch] = append(state.batches[entry.Batch], entry)
+		}
+	}
+	return state
+}
+
+func (state journalState) isUndone(batch string) bool {
+	for _, undone := range state.undone {
+		if undone == batch {
+			return true
+		}
+	}
+	return false
+}
+
+var errNothingToUndo = errors.New("nothing to undo")
+var errNothingToRedo = errors.New("nothing to redo")
+
+// undo reverts the most recent batch that has not been undone yet.
+func (s *journalStore) undo() ([]journalEntry, error) {
+	return s.step(journalUndo)
+}
+
+// redo replays the most recently undone batch, as long as no new change has
+// been made since.
+func (s *journalStore) redo() ([]journalEntry, error) {
+	return s.step(journalRedo)
+}
+
+func (s *journalStore) step(op journalOp) ([]journalEntry, error) {
+	unlock, err := tasks.AcquireLock(s.path+".lock", s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	state := replayJournal(entries)
+
+	stack, empty := state.done, errNothingToUndo
+	if op == journalRedo {
+		stack, empty = state.undone, errNothingToRedo
+	}
+	if len(stack) == 0 {
+		return nil, empty
+	}
+	batch := state.batches[stack[len(stack)-1]]
+
+	// The whole batch is applied in one transaction: if any task has
+	// changed since, nothing is, and the batch stays where it was.
+	err = s.Store.Modify(func(list *[]tasks.Task) error {
+		if op == journalUndo {
+			for i := len(batch) - 1; i >= 0; i-- {
+				entry := batch[i]
+				if err := apply(list, entry.TaskID, entry.After, entry.Before); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, entry := range batch {
+			if err := apply(list, entry.TaskID, entry.Before, entry.After); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	marker := journalEntry{Batch: s.batch, Time: time.Now(), Op: op, Target: batch[0].Batch}
+	return batch, s.append(marker)
+}
+
+// apply changes task id in list from state from to state to, where nil
+// means the task does not exist, refusing when the task is no longer in
+// state from because it has been changed again since.
+func apply(list *[]tasks.Task, id int, from, to *tasks.Task) error {
+	i := indexOf(*list, id)
+	switch {
+	case i < 0 && from != nil:
+		return fmt.Errorf("task %d no longer exists", id)
+	case i >= 0 && from == nil:
+		return fmt.Errorf("task %d already exists", id)
+	case i >= 0 && !(*list)[i].UpdatedAt.Equal(from.UpdatedAt):
+		return fmt.Errorf("task %d has changed since; undo the later change first", id)
+	}
+	switch {
+	case to == nil:
+		*list = slices.Delete(*list, i, i+1)
+	case from == nil:
+		*list = append(*list, *to)
+	default:
+		(*list)[i] = *to
+	}
+	return nil
+}
+
+func (entry journalEntry) describe() string {
+	switch entry.Op {
+	case journalAdd:
+		return fmt.Sprintf("added %q", entry.After.Description)
+	case journalDelete:
+		return fmt.Sprintf("deleted %q", entry.Before.Description)
+	case journalStatus:
+		return fmt.Sprintf("status %s -> %s", entry.Before.Status, entry.After.Status)
+	case journalUpdate:
+		if entry.Before.Description != entry.After.Description {
+			return fmt.Sprintf("description %q -> %q", entry.Before.Description, entry.After.Description)
+		}
+		return fmt.Sprintf("updated %q", entry.After.Description)
+	}
+	return string(entry.Op)
+}
+
+// historyRow is a journal entry as shown by history, undo and redo.
+type historyRow struct {
+	Time   time.Time `json:"time"`
+	Op     journalOp `json:"op"`
+	TaskID int       `json:"taskId"`
+	Change string    `json:"change"`
+	Undone bool      `json:"undone,omitempty"`
+}
+
+func (entry journalEntry) row(undone bool) historyRow {
+	return historyRow{Time: entry.Time, Op: entry.Op, TaskID: entry.TaskID, Change: entry.describe(), Undone: undone}
+}
+
+func printHistoryRows(rows []historyRow) {
+	switch out.format {
+	case outputJSON:
+		if rows == nil {
+			rows = []historyRow{}
+		}
+		printJSON(rows)
+	case outputCSV:
+		records := make([][]string, len(rows))
+		for i, row := range rows {
+			records[i] = []string{row.Time.Format(time.RFC3339), string(row.Op), fmt.Sprint(row.TaskID), row.Change, fmt.Sprint(row.Undone)}
+		}
+		printCSV([]string{"time", "op", "id", "change", "undone"}, records)
+	}
+}
+
+func undoLastChange(store *journalStore) error {
+	batch, err := store.undo()
+	if err != nil {
+		return err
+	}
+	return printStep("Undone", batch, true)
+}
+
+func redoLastChange(store *journalStore) error {
+	batch, err := store.redo()
+	if err != nil {
+		return err
+	}
+	return printStep("Redone", batch, false)
+}
+
+func printStep(verb string, batch []journalEntry, undone bool) error {
+	rows := make([]historyRow, len(batch))
+	for i, entry := range batch {
+		rows[i] = entry.row(undone)
+		out.info("%s: task %d %s", verb, entry.TaskID, rows[i].Change)
+	}
+	if !out.quiet {
+		printHistoryRows(rows)
+	}
+	return nil
+}
+
+// showHistory prints the journal, optionally limited to one task.
+func showHistory(store *journalStore, id int) error {
+	entries, err := store.read()
+	if err != nil {
+		return storageError(fmt.Errorf("reading journal: %w", err))
+	}
+	state := replayJournal(entries)
+
+	var rows []historyRow
+	for _, entry := range entries {
+		if entry.Op == journalUndo || entry.Op == journalRedo {
+			continue
+		}
+		if id != 0 && entry.TaskID != id {
+			continue
+		}
+		rows = append(rows, entry.row(state.isUndone(entry.Batch)))
+	}
+
+	if out.machine() {
+		printHistoryRows(rows)
+		return nil
+	}
+	if len(rows) == 0 {
+		fmt.Println("No history recorded.")
+		return nil
+	}
+	fmt.Printf("%-20s %-8s %-5s %s\n", "Time", "Op", "ID", "Change")
+	fmt.Println("---------------------------------------------------------------")
+	for _, row := range rows {
+		change := row.Change
+		if row.Undone {
+			change += " (undone)"
+		}
+		fmt.Printf("%-20s %-8s %-5d %s\n", row.Time.Format("2006-01-02 15:04:05"), row.Op, row.TaskID, change)
+	}
+	return nil
+}
@@ -1,282 +1,432 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
-)
-
-type StatusTask string
 
-const (
-	StatusTaskTodo       StatusTask = "todo"
-	StatusTaskInProgress StatusTask = "in-progress"
-	StatusTaskDone       StatusTask = "done"
+	"task-cli/tasks"
 )
 
-type Task struct {
-	ID          int        `json:"id"`
-	Description string     `json:"description"`
-	Status      StatusTask `json:"status"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
-}
-
+// fileName is the task file of the global list, and of lists that older
+// versions kept in the working directory.
 const fileName = "tasks.json"
 
-func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		return
-	}
+// stringList is a repeatable flag that also accepts comma separated values.
+type stringList []string
 
-	command := os.Args[1]
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
-	switch command {
-	case "add":
-		if len(os.Args) < 3 {
-			fmt.Println("Error: Task description required")
-			return
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
 		}
-		addTask(os.Args[2])
+	}
+	return nil
+}
 
-	case "list":
-		status := ""
-		if len(os.Args) > 2 {
-			status = os.Args[2]
+// parseSelector reads the target of a command that accepts either a single
+// task ID or a filter expression. It returns id 0 for a filter.
+func parseSelector(args []string) (int, tasks.Filter, error) {
+	if len(args) == 1 {
+		if id, err := strconv.Atoi(args[0]); err == nil {
+			return id, tasks.Filter{}, nil
 		}
-		listTasks(status)
+	}
+	filter, err := tasks.ParseFilter(args, workflow)
+	if err != nil {
+		return 0, tasks.Filter{}, err
+	}
+	if filter.IsEmpty() {
+		return 0, tasks.Filter{}, fmt.Errorf("task ID or filter required")
+	}
+	return 0, filter, nil
+}
 
-	case "update":
-		if len(os.Args) < 4 {
-			fmt.Println("Error: Task ID and new description required")
-			return
+// parseFlags parses flags that may appear before, between or after the
+// positional arguments and returns the positional arguments in order.
+// Exclusion terms such as -tag:blocked look like flags but are positional.
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for len(args) > 0 {
+		if isExclusionTerm(args[0]) {
+			positional = append(positional, args[0])
+			args = args[1:]
+			continue
 		}
-		id, err := strconv.Atoi(os.Args[2])
-		if err != nil {
-			fmt.Println("Error: Invalid ID")
-			return
+		end := slices.IndexFunc(args, isExclusionTerm)
+		if end < 0 {
+			end = len(args)
 		}
-		updateTask(id, os.Args[3])
-
-	case "delete":
-		if len(os.Args) < 3 {
-			fmt.Println("Error: Task ID required")
-			return
+		fs.Parse(args[:end])
+		parsed := fs.Args()
+		args = args[end:]
+		if len(parsed) > 0 {
+			positional = append(positional, parsed[0])
+			args = append(slices.Clone(parsed[1:]), args...)
 		}
-		id, err := strconv.Atoi(os.Args[2])
-		if err != nil {
-			fmt.Println("Error: Invalid ID")
-			return
-		}
-		deleteTask(id)
+	}
+	return positional
+}
+
+// isExclusionTerm reports whether arg is a filter term like -tag:blocked.
+func isExclusionTerm(arg string) bool {
+	field, _, ok := strings.Cut(strings.TrimPrefix(arg, "-"), ":")
+	return ok && strings.HasPrefix(arg, "-") && tasks.IsFilterField(field)
+}
 
-	case "mark-in-progress":
-		if len(os.Args) < 3 {
-			fmt.Println("Error: Task ID required")
-			return
+// globalFlags are the flags every command accepts.
+type globalFlags struct {
+	output output
+	global bool
+	file   string
+}
+
+// parseGlobalFlags removes the global flags from anywhere in args, up to a
+// "--" terminator, and returns the remaining arguments.
+func parseGlobalFlags(args []string) (globalFlags, []string, error) {
+	flags := globalFlags{output: output{format: outputTable}}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
 		}
-		id, err := strconv.Atoi(os.Args[2])
-		if err != nil {
-			fmt.Println("Error: Invalid ID")
-			return
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") {
+			name = ""
+		}
+		switch name {
+		case "quiet", "q":
+			flags.output.quiet = true
+			continue
+		case "global":
+			flags.global = true
+			continue
+		case "output", "file":
+		default:
+			rest = append(rest, arg)
+			continue
 		}
-		updateStatus(id, "in-progress")
 
-	case "mark-done":
-		if len(os.Args) < 3 {
-			fmt.Println("Error: Task ID required")
-			return
+		if !hasValue {
+			if i+1 == len(args) {
+				return flags, nil, invalidInput("--%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "file" {
+			flags.file = value
+			continue
 		}
-		id, err := strconv.Atoi(os.Args[2])
+		format, err := parseOutputFormat(value)
 		if err != nil {
-			fmt.Println("Error: Invalid ID")
-			return
+			return flags, nil, err
 		}
-		updateStatus(id, "done")
-
-	default:
-		printUsage()
+		flags.output.format = format
+	}
+	if flags.global && flags.file != "" {
+		return flags, nil, invalidInput("--global and --file cannot be combined")
 	}
+	return flags, rest, nil
 }
 
-func loadTasks() ([]Task, error) {
-	file, err := os.ReadFile(fileName)
+func main() {
+	// The words to complete may hold unfinished global flags, so they are
+	// left for the completion to read.
+	if len(os.Args) > 1 && os.Args[1] == completeCommand {
+		completeWords(os.Stdout, os.Args[2:])
+		return
+	}
+	flags, args, err := parseGlobalFlags(os.Args[1:])
+	if err == nil {
+		out = flags.output
+		err = run(flags, args)
+	}
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Task{}, nil
-		}
-		return nil, err
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitCode(err))
 	}
+}
 
-	var tasks []Task
-	err = json.Unmarshal(file, &tasks)
+func parseID(value string) (int, error) {
+	id, err := strconv.Atoi(value)
 	if err != nil {
-		return nil, err
+		return 0, invalidInput("invalid ID %q", value)
 	}
-	return tasks, nil
+	return id, nil
+}
+
+// tracker makes changes to the tasks in store under the workflow of the
+// task list.
+func tracker(store tasks.Store) tasks.Tracker {
+	return tasks.Tracker{Store: store, Workflow: workflow}
 }
 
-func saveTasks(tasks []Task) error {
-	data, err := json.MarshalIndent(tasks, "", "  ")
+// withFlagHint names the flag that overrides a refused change.
+func withFlagHint(err error) error {
+	var (
+		blocked  *tasks.BlockedError
+		subtasks *tasks.SubtasksError
+	)
+	switch {
+	case errors.As(err, &blocked):
+		return fmt.Errorf("%w (use --force to move it to %s anyway)", err, blocked.Status)
+	case errors.As(err, &subtasks):
+		return fmt.Errorf("%w; use --cascade to delete them too", err)
+	}
+	return err
+}
+
+func addTask(store tasks.Store, task tasks.Task) error {
+	task, err := tracker(store).Add(task)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(fileName, data, 0644)
+	if out.quiet {
+		fmt.Println(task.ID)
+		return nil
+	}
+	out.info("Task added successfully (ID: %d)", task.ID)
+	out.changed([]tasks.Task{task})
+	return nil
 }
 
-func addTask(description string) {
-	tasks, err := loadTasks()
+func listTasks(store tasks.Store, filter tasks.Filter, sortBy string, tree bool) error {
+	all, err := store.List(tasks.Filter{})
 	if err != nil {
-		fmt.Println("Error loading tasks:", err)
-		return
+		return storageError(err)
 	}
-
-	maxID := 0
-	for _, task := range tasks {
-		if task.ID > maxID {
-			maxID = task.ID
+	var list []tasks.Task
+	for _, task := range all {
+		if filter.Match(task) {
+			list = append(list, task)
 		}
 	}
 
-	newTask := Task{
-		ID:          maxID + 1,
-		Description: description,
-		Status:      StatusTaskTodo,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	switch sortBy {
+	case "priority":
+		sortByPriority(list)
+	case "due":
+		sortByDueDate(list)
 	}
 
-	tasks = append(tasks, newTask)
-
-	if err := saveTasks(tasks); err != nil {
-		fmt.Println("Error saving task:", err)
-		return
+	view := taskView{index: tasks.NewIndex(all)}
+	if tree {
+		list, view.depth = treeOrder(list)
 	}
-	fmt.Printf("Task added successfully (ID: %d)\n", newTask.ID)
+	out.tasks(list, view, "No tasks found.")
+	return nil
 }
 
-func listTasks(filterStatus string) {
-	tasks, err := loadTasks()
+// listDueTasks shows unfinished tasks that are overdue or due within the next days.
+func listDueTasks(store tasks.Store, days int) error {
+	list, err := store.List(tasks.Filter{})
 	if err != nil {
-		fmt.Println("Error loading tasks:", err)
-		return
-	}
-
-	if len(tasks) == 0 {
-		fmt.Println("No tasks found.")
-		return
+		return storageError(err)
 	}
 
-	fmt.Printf("%-5s %-20s %-12s %s\n", "ID", "Status", "Created", "Description")
-	fmt.Println("---------------------------------------------------------------")
-
-	for _, task := range tasks {
-		if filterStatus != "" && task.Status != StatusTask(filterStatus) {
+	limit := tasks.StartOfDay(time.Now()).AddDate(0, 0, days)
+	var due []tasks.Task
+	for _, task := range list {
+		if workflow.IsTerminal(task.Status) || task.DueDate == nil {
 			continue
 		}
-
-		dateStr := task.CreatedAt.Format("2006-01-02")
-		fmt.Printf("%-5d %-20s %-12s %s\n", task.ID, task.Status, dateStr, task.Description)
+		if task.DueDate.After(limit) {
+			continue
+		}
+		due = append(due, task)
 	}
+
+	sortByDueDate(due)
+	out.tasks(due, taskView{index: tasks.NewIndex(list)}, fmt.Sprintf("No tasks due in the next %d days.", days))
+	return nil
 }
 
-func updateTask(id int, description string) {
-	tasks, err := loadTasks()
+func listOverdueTasks(store tasks.Store) error {
+	list, err := store.List(tasks.Filter{})
 	if err != nil {
-		fmt.Println("Error loading tasks:", err)
-		return
+		return storageError(err)
 	}
 
-	found := false
-	for i, task := range tasks {
-		if task.ID == id {
-			tasks[i].Description = description
-			tasks[i].UpdatedAt = time.Now()
-			found = true
-			break
+	var overdue []tasks.Task
+	for _, task := range list {
+		if !workflow.IsTerminal(task.Status) && task.IsOverdue() {
+			overdue = append(overdue, task)
 		}
 	}
 
-	if !found {
-		fmt.Printf("Task with ID %d not found\n", id)
-		return
-	}
+	sortByDueDate(overdue)
+	out.tasks(overdue, taskView{index: tasks.NewIndex(list)}, "No overdue tasks.")
+	return nil
+}
 
-	if err := saveTasks(tasks); err != nil {
-		fmt.Println("Error saving tasks:", err)
-		return
+func sortByPriority(tasks []tasks.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Priority.Rank() > tasks[j].Priority.Rank()
+	})
+}
+
+// sortByDueDate puts the earliest due date first and tasks without one last.
+func sortByDueDate(tasks []tasks.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i].DueDate, tasks[j].DueDate
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Before(*b)
+	})
+}
+
+// taskView carries the optional extras printTasks can show.
+type taskView struct {
+	// index holds every stored task and is used to flag open blockers.
+	index tasks.Index
+	// depth indents subtasks for list --tree.
+	depth map[int]int
+	// highlight lists the description runes search matched, per task.
+	highlight map[int][]int
+}
+
+func printTasks(list []tasks.Task, view taskView) {
+	fmt.Printf("%-5s %-20s %-8s %-12s %-12s %-12s %s\n", "ID", "Status", "Priority", "Due", "Created", "Project", "Description")
+	fmt.Println("---------------------------------------------------------------------------------------------")
+
+	for _, task := range list {
+		priority := string(task.Priority)
+		if priority == "" {
+			priority = "-"
+		}
+		dueStr := "-"
+		if task.DueDate != nil {
+			dueStr = task.DueDate.Format(tasks.DateLayout)
+			if !workflow.IsTerminal(task.Status) && task.IsOverdue() {
+				dueStr += "!"
+			}
+		}
+		project := task.Project
+		if project == "" {
+			project = "-"
+		}
+		description := highlightRunes(task.Description, view.highlight[task.ID])
+		if depth := view.depth[task.ID]; depth > 0 {
+			description = strings.Repeat("  ", depth-1) + "└ " + description
+		}
+		for _, tag := range task.Tags {
+			description += " #" + tag
+		}
+		if task.Recurrence != nil {
+			description += " (repeats " + task.Recurrence.String() + ")"
+		}
+		if open := view.index.OpenBlockers(task, workflow); len(open) > 0 {
+			description += " [blocked by " + tasks.JoinIDs(open) + "]"
+		}
+		dateStr := task.CreatedAt.Format(tasks.DateLayout)
+		fmt.Printf("%-5d %-20s %-8s %-12s %-12s %-12s %s\n", task.ID, task.Status, priority, dueStr, dateStr, project, description)
 	}
-	fmt.Println("Task updated successfully")
 }
 
-func updateStatus(id int, status string) {
-	tasks, err := loadTasks()
+func updateTask(store tasks.Store, id int, update tasks.Update) error {
+	task, err := tracker(store).Edit(id, update)
 	if err != nil {
-		fmt.Println("Error loading tasks:", err)
-		return
+		return err
 	}
+	out.info("Task updated successfully")
+	out.changed([]tasks.Task{task})
+	return nil
+}
 
-	found := false
-	for i, task := range tasks {
-		if task.ID == id {
-			tasks[i].Status = StatusTask(status)
-			tasks[i].UpdatedAt = time.Now()
-			found = true
-			break
-		}
+func updateStatus(store tasks.Store, id int, status tasks.StatusTask, force bool) error {
+	change, err := tracker(store).SetStatus(id, status, force)
+	if err != nil {
+		return withFlagHint(err)
 	}
-
-	if !found {
-		fmt.Printf("Task with ID %d not found\n", id)
-		return
+	if len(change.OpenBlockers) > 0 {
+		warn("Task %d is still blocked by open task(s) %s", id, tasks.JoinIDs(change.OpenBlockers))
+	}
+	if change.OpenSubtasks > 0 {
+		warn("Task %d still has %d open subtask(s)", id, change.OpenSubtasks)
+	}
+	if change.Stopped > 0 {
+		out.info("Timer stopped after %s", formatDuration(change.Stopped))
 	}
+	out.info("Task status updated successfully")
+	printFollowUps(change.FollowUps)
+	out.changed(append([]tasks.Task{change.Task}, change.FollowUps...))
+	return nil
+}
 
-	if err := saveTasks(tasks); err != nil {
-		fmt.Println("Error saving tasks:", err)
-		return
+func printFollowUps(created []tasks.Task) {
+	for _, next := range created {
+		out.info("Next occurrence added (ID: %d, due %s)", next.ID, next.DueDate.Format(tasks.DateLayout))
 	}
-	fmt.Println("Task status updated successfully")
 }
 
-func deleteTask(id int) {
-	tasks, err := loadTasks()
+func deleteTask(store tasks.Store, id int, cascade bool) error {
+	deleted, err := tracker(store).Remove(id, cascade)
 	if err != nil {
-		fmt.Println("Error loading tasks:", err)
-		return
+		return withFlagHint(err)
 	}
-
-	var newTasks []Task
-	found := false
-	for _, task := range tasks {
-		if task.ID == id {
-			found = true
-			continue
-		}
-		newTasks = append(newTasks, task)
+	if len(deleted) > 1 {
+		out.info("Task deleted successfully (with %d subtask(s))", len(deleted)-1)
+	} else {
+		out.info("Task deleted successfully")
 	}
+	out.changed(deleted)
+	return nil
+}
 
-	if !found {
-		fmt.Printf("Task with ID %d not found\n", id)
-		return
+func updateMatchingStatus(store tasks.Store, filter tasks.Filter, status tasks.StatusTask, force bool) error {
+	result, err := tracker(store).SetStatusMatching(filter, status, force)
+	if err != nil {
+		return err
+	}
+	for _, skipped := range result.Skipped {
+		var (
+			transition *tasks.TransitionError
+			blocked    *tasks.BlockedError
+		)
+		switch {
+		case errors.As(skipped, &transition):
+			warn("Skipping task %d: the workflow does not allow moving from %s to %s", transition.ID, transition.From, transition.To)
+		case errors.As(skipped, &blocked):
+			warn("Skipping task %d: blocked by open task(s) %s", blocked.ID, tasks.JoinIDs(blocked.Blockers))
+		}
 	}
 
-	if err := saveTasks(newTasks); err != nil {
-		fmt.Println("Error saving tasks:", err)
-		return
+	if len(result.Changed) == 0 {
+		out.info("No matching tasks to update")
+		out.changed(nil)
+		return nil
 	}
-	fmt.Println("Task deleted successfully")
+	out.info("%d task(s) marked as %s", len(result.Changed), status)
+	printFollowUps(result.FollowUps)
+	out.changed(append(result.Changed, result.FollowUps...))
+	return nil
 }
 
-func printUsage() {
-	fmt.Println("Usage: task-cli [command] [arguments]")
-	fmt.Println("Commands:")
-	fmt.Println("  add \"description\"            	Add a new task")
-	fmt.Println("  list [status]                	List tasks (optional: done, todo, in-progress)")
-	fmt.Println("  update [id] \"description\"    	Update a task description")
-	fmt.Println("  delete [id]                  	Delete a task")
-	fmt.Println("  mark-in-progress [id]        	Mark task as in-progress")
-	fmt.Println("  mark-done [id]               	Mark task as done")
+func deleteMatchingTasks(store tasks.Store, filter tasks.Filter, cascade bool) error {
+	deleted, err := tracker(store).RemoveMatching(filter, cascade)
+	if err != nil {
+		return withFlagHint(err)
+	}
+	if len(deleted) == 0 {
+		out.info("No matching tasks to delete")
+		out.changed(nil)
+		return nil
+	}
+	out.info("%d task(s) deleted", len(deleted))
+	out.changed(deleted)
+	return nil
 }
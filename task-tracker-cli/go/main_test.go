@@ -0,0 +1,568 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"task-cli/tasks"
+)
+
+func TestQuietAddPrintsOnlyTheID(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "add", "first")
+
+	stdout, stderr, code := runCLIStatus(t, dir, "add", "--quiet", "second")
+	if code != 0 || stdout != "2\n" || stderr != "" {
+		t.Errorf("add --quiet = %q, %q, exit %d; want \"2\\n\" and nothing else", stdout, stderr, code)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "add", "write docs", "--tag", "docs,api")
+	runCLI(t, dir, "add", "ship it", "--priority", "high")
+
+	stdout, _, _ := runCLIStatus(t, dir, "--output", "json", "list")
+	var list []tasks.Task
+	if err := json.Unmarshal([]byte(stdout), &list); err != nil {
+		t.Fatalf("list --output json is not valid JSON: %v\n%s", err, stdout)
+	}
+	if len(list) != 2 || list[0].Tags[1] != "api" || list[1].Priority != tasks.PriorityHigh {
+		t.Errorf("list --output json = %+v", list)
+	}
+
+	stdout, _, _ = runCLIStatus(t, dir, "list", "--output=csv", "tag:docs")
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatalf("list --output csv is not valid CSV: %v\n%s", err, stdout)
+	}
+	if len(records) != 2 || records[1][0] != "1" || records[1][5] != "docs,api" {
+		t.Errorf("list --output csv = %q", records)
+	}
+
+	stdout, _, _ = runCLIStatus(t, dir, "mark-done", "2", "--output", "json")
+	if err := json.Unmarshal([]byte(stdout), &list); err != nil || len(list) != 1 || list[0].Status != tasks.StatusTaskDone {
+		t.Errorf("mark-done --output json = %s (%v)", stdout, err)
+	}
+}
+
+func TestListWithExclusionTerm(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "add", "ready", "--project", "api", "--tag", "backend")
+	runCLI(t, dir, "add", "waiting", "--project", "api", "--tag", "backend,blocked")
+	runCLI(t, dir, "add", "elsewhere", "--project", "web", "--tag", "backend")
+
+	tests := [][]string{
+		{"list", "status:todo", "tag:backend", "project:api", "-tag:blocked"},
+		{"list", "-tag:blocked", "--sort", "due", "project:api"},
+		{"list", "project:api", "--sort=due", "-tag:blocked", "--tree"},
+	}
+	for _, args := range tests {
+		stdout, stderr, code := runCLIStatus(t, dir, append(args, "--output", "json")...)
+		var list []tasks.Task
+		if code != 0 || json.Unmarshal([]byte(stdout), &list) != nil {
+			t.Errorf("task-cli %s exited %d: %s", strings.Join(args, " "), code, stderr)
+			continue
+		}
+		if len(list) != 1 || list[0].ID != 1 {
+			t.Errorf("task-cli %s = %+v, want only task 1", strings.Join(args, " "), list)
+		}
+	}
+}
+
+func TestBulkChangesWithExclusionTerm(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "one", "--project", "api")
+	runCLI(t, dir, "add", "two", "--project", "api", "--tag", "blocked")
+	runCLI(t, dir, "add", "three", "--project", "api")
+	runCLI(t, dir, "add", "four", "--project", "web")
+
+	runCLI(t, dir, "mark-done", "project:api", "-tag:blocked", "--force")
+	status := map[int]tasks.StatusTask{}
+	for _, task := range readTaskFile(t, dir) {
+		status[task.ID] = task.Status
+	}
+	want := map[int]tasks.StatusTask{1: tasks.StatusTaskDone, 2: tasks.StatusTaskTodo, 3: tasks.StatusTaskDone, 4: tasks.StatusTaskTodo}
+	if !maps.Equal(status, want) {
+		t.Errorf("statuses after mark-done project:api -tag:blocked = %v, want %v", status, want)
+	}
+
+	runCLI(t, dir, "delete", "project:api", "-tag:blocked", "--cascade")
+	var left []int
+	for _, task := range readTaskFile(t, dir) {
+		left = append(left, task.ID)
+	}
+	if !slices.Equal(left, []int{2, 4}) {
+		t.Errorf("tasks left after delete project:api -tag:blocked = %v, want [2 4]", left)
+	}
+}
+
+func TestErrorsUseExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "add", "only task")
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"update", "7", "new text"}, exitNotFound},
+		{[]string{"mark-done", "7"}, exitNotFound},
+		{[]string{"add"}, exitInvalid},
+		{[]string{"update", "one", "new text"}, exitInvalid},
+		{[]string{"list", "--output", "yaml"}, exitInvalid},
+		{[]string{"frobnicate"}, exitInvalid},
+		{[]string{"stop"}, exitFailed},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := runCLIStatus(t, dir, tt.args...)
+		if code != tt.code {
+			t.Errorf("task-cli %s exited %d, want %d", strings.Join(tt.args, " "), code, tt.code)
+		}
+		if !strings.HasPrefix(stderr, "Error: ") && !strings.Contains(stderr, "\nError: ") {
+			t.Errorf("task-cli %s wrote no error to stderr: %q", strings.Join(tt.args, " "), stderr)
+		}
+		if strings.Contains(stdout, "Error") {
+			t.Errorf("task-cli %s wrote the error to stdout: %q", strings.Join(tt.args, " "), stdout)
+		}
+	}
+}
+
+func TestExportToFile(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "work.json")
+	runCLI(t, dir, "--file", list, "add", "write report", "--project", "api")
+	runCLI(t, dir, "add", "not exported")
+
+	out := filepath.Join(dir, "tasks.md")
+	runCLI(t, dir, "export", "--file", list, "--out", out)
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("export --out wrote no file: %v", err)
+	}
+	if got := string(data); !strings.Contains(got, "- [ ] write report") || strings.Contains(got, "not exported") {
+		t.Errorf("export of %s =\n%s", list, got)
+	}
+}
+
+func TestConcurrentImportsAddEachTaskOnce(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "plan release")
+	file := filepath.Join(dir, "tasks.md")
+	checklist := "- [ ] plan release\n- [ ] write notes\n  - [x] collect changes\n"
+	if err := os.WriteFile(file, []byte(checklist), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runConcurrently(5, func(int) {
+		runCLI(t, dir, "import", file)
+	})
+
+	list := readTaskFile(t, dir)
+	var descriptions []string
+	for _, task := range list {
+		descriptions = append(descriptions, task.Description)
+	}
+	if !slices.Equal(descriptions, []string{"plan release", "write notes", "collect changes"}) {
+		t.Fatalf("tasks after 5 imports = %q, want each once", descriptions)
+	}
+	if list[2].ParentID != list[1].ID || list[2].Status != tasks.StatusTaskDone {
+		t.Errorf("imported subtask = %+v, want a done subtask of task %d", list[2], list[1].ID)
+	}
+}
+
+func TestStoreDiscovery(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// cliEnv puts the global list under the directory the command runs in.
+	global := filepath.Join(nested, "data", globalDirName, fileName)
+	if got := strings.TrimSpace(runCLI(t, nested, "where")); got != global {
+		t.Errorf("where outside a project = %s, want the global list %s", got, global)
+	}
+
+	runCLI(t, dir, "init")
+	runCLI(t, nested, "add", "found from below")
+	if tasks := readTaskFile(t, dir); len(tasks) != 1 || tasks[0].Description != "found from below" {
+		t.Errorf("project tasks = %+v, want the task added from a subdirectory", tasks)
+	}
+
+	runCLI(t, nested, "add", "--global", "personal")
+	if got := strings.TrimSpace(runCLI(t, nested, "where", "--global")); got != global {
+		t.Errorf("where --global = %s, want %s", got, global)
+	}
+	if _, err := os.Stat(global); err != nil {
+		t.Errorf("add --global did not write the global list: %v", err)
+	}
+
+	other := filepath.Join(dir, "other.json")
+	runCLI(t, nested, "--file", other, "add", "elsewhere")
+	if stdout, _, _ := runCLIStatus(t, nested, "list", "--file="+other, "--output", "json"); !strings.Contains(stdout, "elsewhere") || strings.Contains(stdout, "personal") {
+		t.Errorf("list --file %s = %s", other, stdout)
+	}
+}
+
+func TestUndoRevertsWholeBatch(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "first")
+	runCLI(t, dir, "add", "second")
+	runCLI(t, dir, "mark-done", "status:todo")
+
+	// Task 1 is changed behind the journal's back. Undo reverts task 2
+	// first, then finds task 1 changed: neither may be reverted.
+	list := readTaskFile(t, dir)
+	list[0].UpdatedAt = list[0].UpdatedAt.Add(time.Second)
+	data, _ := json.Marshal(list)
+	if err := os.WriteFile(filepath.Join(dir, projectFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, code := runCLIStatus(t, dir, "undo"); code == 0 || !strings.Contains(stderr, "task 1 has changed") {
+		t.Errorf("undo over a changed task exited %d: %s", code, stderr)
+	}
+	for _, task := range readTaskFile(t, dir) {
+		if task.Status != tasks.StatusTaskDone {
+			t.Errorf("task %d is %s after a failed undo, want the batch left done", task.ID, task.Status)
+		}
+	}
+
+	list[0].UpdatedAt = list[0].UpdatedAt.Add(-time.Second)
+	data, _ = json.Marshal(list)
+	if err := os.WriteFile(filepath.Join(dir, projectFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, dir, "undo")
+	for _, task := range readTaskFile(t, dir) {
+		if task.Status != tasks.StatusTaskTodo {
+			t.Errorf("task %d is %s after undo, want todo", task.ID, task.Status)
+		}
+	}
+}
+
+func TestArchiveRestoreAndPurge(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "parent")
+	runCLI(t, dir, "add", "child", "--parent", "1")
+	runCLI(t, dir, "add", "still open")
+	runCLI(t, dir, "mark-done", "1")
+
+	// The parent waits for its open subtask.
+	runCLI(t, dir, "archive", "--days", "0")
+	if list := readTaskFile(t, dir); len(list) != 3 {
+		t.Fatalf("archive took a parent with an open subtask: %+v", list)
+	}
+
+	runCLI(t, dir, "mark-done", "2")
+	runCLI(t, dir, "archive", "--days", "0")
+	if list := readTaskFile(t, dir); len(list) != 1 || list[0].ID != 3 {
+		t.Errorf("tasks after archive = %+v, want only task 3", list)
+	}
+	stdout, _, _ := runCLIStatus(t, dir, "list", "--archived", "--output", "json")
+	var archived []tasks.Task
+	if err := json.Unmarshal([]byte(stdout), &archived); err != nil || len(archived) != 2 {
+		t.Fatalf("list --archived = %s (%v)", stdout, err)
+	}
+
+	runCLI(t, dir, "restore", "2")
+	if list := readTaskFile(t, dir); len(list) != 2 || list[1].ID != 2 || list[1].ParentID != 0 {
+		t.Errorf("tasks after restore = %+v, want task 2 back without its archived parent", list)
+	}
+	if _, _, code := runCLIStatus(t, dir, "restore", "2"); code != exitNotFound {
+		t.Errorf("restoring task 2 twice exited %d, want %d", code, exitNotFound)
+	}
+
+	runCLI(t, dir, "purge", "--before", "2000-01-01")
+	runCLI(t, dir, "purge", "--before", "2999-01-01")
+	if stdout := runCLI(t, dir, "list", "--archived"); !strings.Contains(stdout, "No tasks found.") {
+		t.Errorf("list --archived after purge = %q", stdout)
+	}
+}
+
+func TestShowNotesAndStatusLog(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "write the report")
+	runCLI(t, dir, "note", "add", "1", "ask for the figures")
+	runCLI(t, dir, "mark-in-progress", "1")
+	runCLI(t, dir, "mark-done", "1")
+
+	stdout := runCLI(t, dir, "show", "1")
+	for _, want := range []string{"Task 1: write the report", "    ask for the figures", "todo -> in-progress", "in-progress -> done"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("show 1 does not contain %q:\n%s", want, stdout)
+		}
+	}
+
+	list := readTaskFile(t, dir)
+	if log := list[0].StatusLog; len(log) != 2 || log[1].To != tasks.StatusTaskDone || log[1].At.IsZero() {
+		t.Errorf("status log = %+v", log)
+	}
+}
+
+func TestWorkflowTransitions(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	workflow := `{
+		"statuses": ["todo", "doing", "review", "done"],
+		"terminal": ["done"],
+		"transitions": {"todo": ["doing"], "doing": ["review"], "review": ["doing", "done"]}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, ".tasks.workflow.json"), []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, dir, "add", "first")
+	runCLI(t, dir, "add", "second", "--blocked-by", "1")
+
+	stdout, _, _ := runCLIStatus(t, dir, "--output", "csv", "workflow")
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatalf("workflow --output csv is not valid CSV: %v\n%s", err, stdout)
+	}
+	want := [][]string{
+		{"status", "initial", "terminal", "next"},
+		{"todo", "true", "false", "doing"},
+		{"doing", "false", "false", "review"},
+		{"review", "false", "false", "doing,done"},
+		{"done", "false", "true", ""},
+	}
+	if !slices.EqualFunc(records, want, slices.Equal) {
+		t.Errorf("workflow --output csv = %q, want %q", records, want)
+	}
+
+	if _, stderr, code := runCLIStatus(t, dir, "mark-done", "1"); code != exitFailed || !strings.Contains(stderr, "allowed: doing") {
+		t.Errorf("skipping review exited %d: %s", code, stderr)
+	}
+	if _, _, code := runCLIStatus(t, dir, "move", "1", "in-progress"); code != exitInvalid {
+		t.Errorf("moving to a status outside the workflow exited %d, want %d", code, exitInvalid)
+	}
+	runCLI(t, dir, "move", "1", "doing")
+	runCLI(t, dir, "move", "status:doing", "review")
+	runCLI(t, dir, "move", "1", "done")
+
+	tasks := readTaskFile(t, dir)
+	if tasks[0].Status != "done" || len(tasks[0].StatusLog) != 3 {
+		t.Errorf("task 1 = %+v, want done after three moves", tasks[0])
+	}
+	// The task is finished, so it no longer blocks the second one.
+	if stdout := runCLI(t, dir, "list", "todo"); strings.Contains(stdout, "blocked by") {
+		t.Errorf("task 2 still shows as blocked:\n%s", stdout)
+	}
+}
+
+func TestParseDatePhrase(t *testing.T) {
+	// A Wednesday, late in the day.
+	now := time.Date(2026, 10, 14, 22, 30, 0, 0, time.Local)
+	tests := map[string]string{
+		"today":       "2026-10-14",
+		"Tomorrow":    "2026-10-15",
+		"yesterday":   "2026-10-13",
+		"fri":         "2026-10-16",
+		"next fri":    "2026-10-16",
+		"wednesday":   "2026-10-21",
+		"in 3 days":   "2026-10-17",
+		"in a week":   "2026-10-21",
+		"in 2 months": "2026-12-14",
+		"next month":  "2026-11-14",
+	}
+	for phrase, want := range tests {
+		got, ok := parseDatePhrase(phrase, now)
+		if !ok || got.Format(tasks.DateLayout) != want {
+			t.Errorf("parseDatePhrase(%q) = %s, %v; want %s", phrase, got.Format(tasks.DateLayout), ok, want)
+		}
+	}
+	// Months are added without running past the end of a shorter month.
+	endOfMonth := map[string]string{
+		"next month":   "2027-02-28",
+		"in 1 month":   "2027-02-28",
+		"in 3 months":  "2027-04-30",
+		"in 13 months": "2028-02-29",
+	}
+	for phrase, want := range endOfMonth {
+		got, ok := parseDatePhrase(phrase, time.Date(2027, 1, 31, 9, 0, 0, 0, time.Local))
+		if !ok || got.Format(tasks.DateLayout) != want {
+			t.Errorf("parseDatePhrase(%q) on Jan 31 = %s, %v; want %s", phrase, got.Format(tasks.DateLayout), ok, want)
+		}
+	}
+	for _, phrase := range []string{"someday", "in days", "next year", "in -2 days"} {
+		if _, ok := parseDatePhrase(phrase, now); ok {
+			t.Errorf("parseDatePhrase(%q) succeeded, want it refused", phrase)
+		}
+	}
+}
+
+func TestReadTodoTxtDates(t *testing.T) {
+	data := "(A) today write report\nmon meeting prep due:2026-11-02\nx 2026-10-12 2026-10-01 fri deploy created:2026-09-30\n"
+	list, err := readTodoTxt([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only YYYY-MM-DD dates are read as dates; phrases stay in the
+	// description.
+	tests := []struct {
+		description, created string
+	}{
+		{"today write report", time.Now().Format(tasks.DateLayout)},
+		{"mon meeting prep", time.Now().Format(tasks.DateLayout)},
+		{"fri deploy", "2026-09-30"},
+	}
+	for i, tt := range tests {
+		task := list[i]
+		if task.Description != tt.description || task.CreatedAt.Format(tasks.DateLayout) != tt.created {
+			t.Errorf("line %d = %q created %s, want %q created %s", i+1, task.Description, task.CreatedAt.Format(tasks.DateLayout), tt.description, tt.created)
+		}
+	}
+	if done := list[2].UpdatedAt.Format(tasks.DateLayout); done != "2026-10-12" {
+		t.Errorf("line 3 completed %s, want 2026-10-12", done)
+	}
+	if _, err := readTodoTxt([]byte("call back due:tomorrow\n")); err == nil {
+		t.Error("due:tomorrow was accepted, want YYYY-MM-DD only")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "write docs", "--tag", "docs")
+	runCLI(t, dir, "add", "ship it", "--due", "tomorrow")
+
+	complete := func(words ...string) []string {
+		stdout := runCLI(t, dir, append([]string{completeCommand}, words...)...)
+		var values []string
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+			value, _, _ := strings.Cut(line, "\t")
+			values = append(values, value)
+		}
+		return values
+	}
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"mark-"}, []string{"mark-in-progress", "mark-done"}},
+		{[]string{"show", ""}, []string{"1", "2"}},
+		{[]string{"move", "1", "in"}, []string{"in-progress"}},
+		{[]string{"move", "1", ""}, []string{"todo", "in-progress", "done"}},
+		{[]string{"move", "tag:docs", "do"}, []string{"done"}},
+		{[]string{"list", "tag:"}, []string{"tag:docs"}},
+		{[]string{"add", "x", "--due=tom"}, []string{"--due=tomorrow"}},
+		{[]string{"list", "--output", "j"}, []string{"json"}},
+		{[]string{"note", ""}, []string{"add"}},
+	}
+	for _, tt := range tests {
+		if got := complete(tt.words...); !slices.Equal(got, tt.want) {
+			t.Errorf("completing %q = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+	if script := runCLI(t, dir, "completion", "bash"); !strings.Contains(script, "__complete") {
+		t.Errorf("completion bash printed no script:\n%s", script)
+	}
+}
+
+func TestBuildStats(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2026, 10, d, hour, 0, 0, 0, time.Local) }
+	list := []tasks.Task{
+		// Done two days after creation, logged.
+		{ID: 1, Status: tasks.StatusTaskDone, CreatedAt: day(5, 9), UpdatedAt: day(9, 9),
+			StatusLog: []tasks.StatusLogEntry{{From: tasks.StatusTaskTodo, To: tasks.StatusTaskDone, At: day(7, 9)}}},
+		// Done before status logs existed, so UpdatedAt counts.
+		{ID: 2, Status: tasks.StatusTaskDone, CreatedAt: day(6, 9), UpdatedAt: day(10, 9)},
+		// Finished once, then reopened.
+		{ID: 3, Status: tasks.StatusTaskInProgress, CreatedAt: day(6, 12), UpdatedAt: day(9, 12),
+			StatusLog: []tasks.StatusLogEntry{{From: tasks.StatusTaskTodo, To: tasks.StatusTaskDone, At: day(8, 12)}, {From: tasks.StatusTaskDone, To: tasks.StatusTaskInProgress, At: day(9, 12)}}},
+		{ID: 4, Status: tasks.StatusTaskTodo, CreatedAt: day(12, 9), UpdatedAt: day(12, 9)},
+	}
+	stats := buildStats(list, day(5, 0), day(12, 0))
+
+	var open []int
+	for _, d := range stats.Days {
+		open = append(open, d.Open)
+	}
+	if want := []int{1, 3, 2, 1, 2, 1, 1, 2}; !slices.Equal(open, want) {
+		t.Errorf("open per day = %v, want %v", open, want)
+	}
+	// 2026-10-05 is a Monday.
+	if len(stats.Weeks) != 2 || stats.Weeks[0] != (statsWeek{"2026-10-05", 3, 2}) || stats.Weeks[1] != (statsWeek{"2026-10-12", 1, 0}) {
+		t.Errorf("weeks = %+v", stats.Weeks)
+	}
+	if stats.Finished != 2 || stats.LeadTime != 3*24*time.Hour {
+		t.Errorf("lead time = %s over %d task(s), want 72h over 2", stats.LeadTime, stats.Finished)
+	}
+	if want := []statusCount{{tasks.StatusTaskTodo, 1}, {tasks.StatusTaskInProgress, 1}}; !slices.Equal(stats.Open, want) {
+		t.Errorf("open by status = %+v, want %+v", stats.Open, want)
+	}
+}
+
+// recordingNotifier records reminders, or fails while failing is set.
+type recordingNotifier struct {
+	id       string
+	failing  bool
+	messages []string
+}
+
+func (n *recordingNotifier) name() string { return n.id }
+
+func (n *recordingNotifier) notify(r reminder) error {
+	if n.failing {
+		return errors.New("unreachable")
+	}
+	n.messages = append(n.messages, r.Before+" "+r.Message)
+	return nil
+}
+
+func TestSendReminders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := tasks.NewJSONStore(path)
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	if _, err := store.Create(
+		tasks.Task{Description: "pay rent", Status: tasks.StatusTaskTodo, DueDate: &due},
+		tasks.Task{Description: "no date", Status: tasks.StatusTaskTodo},
+	); err != nil {
+		t.Fatal(err)
+	}
+	offsets, err := parseOffsets("0,1d,1w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, hook := &recordingNotifier{id: "log"}, &recordingNotifier{id: "hook", failing: true}
+	send := func(now time.Time) {
+		t.Helper()
+		state, err := loadReminderState(locationForFile(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sendReminders(store, state, offsets, []notifier{log, hook}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Started long after the one-week reminder was due: only the latest
+	// one goes out, and not again after a restart.
+	send(time.Date(2026, 10, 20, 9, 0, 0, 0, time.Local))
+	send(time.Date(2026, 10, 20, 10, 0, 0, 0, time.Local))
+	if want := []string{`1d task 1 "pay rent" is due today`}; !slices.Equal(log.messages, want) {
+		t.Errorf("log got %q, want %q", log.messages, want)
+	}
+	// The failed notifier is retried until it gets through.
+	hook.failing = false
+	send(time.Date(2026, 10, 20, 11, 0, 0, 0, time.Local))
+	send(time.Date(2026, 10, 21, 1, 0, 0, 0, time.Local))
+	if want := []string{`1d task 1 "pay rent" is due today`, `0 task 1 "pay rent" is overdue (due 2026-10-20)`}; !slices.Equal(hook.messages, want) {
+		t.Errorf("hook got %q, want %q", hook.messages, want)
+	}
+	if len(log.messages) != 2 {
+		t.Errorf("log got %q, want the overdue reminder added", log.messages)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"task-cli/tasks"
+)
+
+const timeLayout = "2006-01-02 15:04"
+
+func addNote(store tasks.Store, id int, text string) error {
+	task, err := store.Get(id)
+	if err != nil {
+		return storeError(id, err)
+	}
+	if text == "" {
+		if text, err = editNote(task); err != nil {
+			return err
+		}
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return invalidInput("empty note, nothing added")
+	}
+
+	// The editor may have been open for a while, so the note is added to
+	// the task as stored now rather than as it was read.
+	err = store.Modify(func(list *[]tasks.Task) error {
+		i := slices.IndexFunc(*list, func(t tasks.Task) bool { return t.ID == id })
+		if i < 0 {
+			return tasks.ErrNotFound
+		}
+		now := time.Now()
+		task = (*list)[i]
+		task.Notes = append(task.Notes, tasks.Note{Text: text, CreatedAt: now})
+		task.UpdatedAt = now
+		(*list)[i] = task
+		return nil
+	})
+	if err != nil {
+		return storeError(id, err)
+	}
+	out.info("Note added to task %d", id)
+	out.changed([]tasks.Task{task})
+	return nil
+}
+
+// editNote opens $VISUAL or $EDITOR (vi when neither is set) on a
+// temporary file and returns what was written, without the comment lines.
+func editNote(task tasks.Task) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	file, err := os.CreateTemp("", "task-note-*.txt")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	defer os.Remove(path)
+	fmt.Fprintf(file, "\n# Note for task %d: %s\n# Lines starting with # are ignored; an empty note is not added.\n", task.ID, task.Description)
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	// The editor may come with arguments, as in "code --wait".
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor %q: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// showTask prints everything known about a task: its fields, links,
+// tracked time, notes and status log.
+func showTask(store tasks.Store, id int) error {
+	all, err := store.List(tasks.Filter{})
+	if err != nil {
+		return storageError(err)
+	}
+	index := tasks.NewIndex(all)
+	task, ok := index[id]
+	if !ok {
+		return notFound(id)
+	}
+	if out.machine() {
+		out.tasks([]tasks.Task{task}, taskView{}, "")
+		return nil
+	}
+
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Printf("%-12s %s\n", name+":", value)
+		}
+	}
+	fmt.Printf("Task %d: %s\n", task.ID, task.Description)
+	field("Status", string(task.Status))
+	field("Priority", string(task.Priority))
+	if task.DueDate != nil {
+		due := task.DueDate.Format(tasks.DateLayout)
+		if !workflow.IsTerminal(task.Status) && task.IsOverdue() {
+			due += " (overdue)"
+		}
+		field("Due", due)
+	}
+	field("Project", task.Project)
+	field("Tags", strings.Join(task.Tags, ", "))
+	if parent, ok := index[task.ParentID]; ok {
+		field("Parent", fmt.Sprintf("%d %s", parent.ID, parent.Description))
+	}
+	var subtasks []int
+	for _, child := range index.Children(task.ID) {
+		subtasks = append(subtasks, child.ID)
+	}
+	sort.Ints(subtasks)
+	field("Subtasks", tasks.JoinIDs(subtasks))
+	if len(task.BlockedBy) > 0 {
+		blockers := tasks.JoinIDs(task.BlockedBy)
+		if open := index.OpenBlockers(task, workflow); len(open) > 0 {
+			blockers += " (open: " + tasks.JoinIDs(open) + ")"
+		}
+		field("Blocked by", blockers)
+	}
+	if task.Recurrence != nil {
+		field("Repeats", task.Recurrence.String())
+	}
+	if len(task.TimeLog) > 0 {
+		now := time.Now()
+		tracked := formatDuration(task.TrackedTime(time.Time{}, now, now))
+		if task.TimerRunning() {
+			tracked += " (timer running)"
+		}
+		field("Tracked", tracked)
+	}
+	field("UID", task.UID)
+	field("Created", task.CreatedAt.Format(timeLayout))
+	field("Updated", task.UpdatedAt.Format(timeLayout))
+
+	if len(task.Notes) > 0 {
+		fmt.Println("\nNotes:")
+		for _, note := range task.Notes {
+			fmt.Printf("  %s\n", note.CreatedAt.Format(timeLayout))
+			for _, line := range strings.Split(note.Text, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	if len(task.StatusLog) > 0 {
+		fmt.Println("\nStatus log:")
+		for _, entry := range task.StatusLog {
+			fmt.Printf("  %s  %s -> %s\n", entry.At.Format(timeLayout), entry.From, entry.To)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-cli/tasks"
+)
+
+// Exit codes, so scripts can tell failures apart without parsing messages.
+const (
+	exitFailed   = 1 // the command was refused, e.g. a blocked task or a journal conflict
+	exitInvalid  = 2 // bad arguments or flags
+	exitNotFound = 3 // the task does not exist
+	exitStorage  = 4 // the task store could not be read or written
+)
+
+// cliError is an error that decides the exit code of the command.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string { return e.err.Error() }
+func (e *cliError) Unwrap() error { return e.err }
+
+func invalidInput(format string, args ...any) error {
+	return &cliError{code: exitInvalid, err: fmt.Errorf(format, args...)}
+}
+
+func notFound(id int) error {
+	return &cliError{code: exitNotFound, err: fmt.Errorf("task with ID %d not found", id)}
+}
+
+// storageError marks err as a failure of the task store. Missing tasks and
+// errors that already carry an exit code keep theirs.
+func storageError(err error) error {
+	if err == nil || exitCode(err) != exitFailed {
+		return err
+	}
+	return &cliError{code: exitStorage, err: err}
+}
+
+// exitCode picks the exit code for err, including the typed errors of the
+// tasks package.
+func exitCode(err error) int {
+	var (
+		cliErr    *cliError
+		storeErr  *tasks.StoreError
+		statusErr *tasks.InvalidStatusError
+		linkErr   *tasks.LinkError
+	)
+	switch {
+	case errors.As(err, &cliErr):
+		return cliErr.code
+	case errors.Is(err, tasks.ErrNotFound):
+		return exitNotFound
+	case errors.As(err, &storeErr):
+		return exitStorage
+	case errors.As(err, &statusErr), errors.As(err, &linkErr):
+		return exitInvalid
+	}
+	return exitFailed
+}
+
+type outputFormat string
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	switch format := outputFormat(value); format {
+	case outputTable, outputCSV, outputJSON:
+		return format, nil
+	}
+	return "", invalidInput("invalid output format %q (use table, csv or json)", value)
+}
+
+const (
+	outputTable outputFormat = "table"
+	outputCSV   outputFormat = "csv"
+	outputJSON  outputFormat = "json"
+)
+
+// output holds the global --output and --quiet flags. Tables and messages
+// are meant for people; json and csv print the data a command produced, or
+// the tasks it changed, and nothing else.
+type output struct {
+	format outputFormat
+	quiet  bool
+}
+
+var out = output{format: outputTable}
+
+func (o output) machine() bool {
+	return o.format != outputTable
+}
+
+// info prints a message for people reading the terminal.
+func (o output) info(format string, args ...any) {
+	if o.machine() || o.quiet {
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// warn always goes to stderr so it never mixes with json or csv output.
+func warn(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// changed prints the tasks a command created or modified in json and csv
+// mode. Tables rely on the messages printed with info instead.
+func (o output) changed(tasks []tasks.Task) {
+	if !o.machine() || o.quiet {
+		return
+	}
+	o.tasks(tasks, taskView{}, "")
+}
+
+// tasks prints a task list in the selected format. empty is shown on a
+// terminal instead of an empty table.
+func (o output) tasks(list []tasks.Task, view taskView, empty string) {
+	switch o.format {
+	case outputJSON:
+		if list == nil {
+			list = []tasks.Task{}
+		}
+		printJSON(list)
+	case outputCSV:
+		rows := make([][]string, len(list))
+		for i, task := range list {
+			rows[i] = taskCSVRow(task)
+		}
+		printCSV(taskCSVHeader, rows)
+	default:
+		if len(list) == 0 {
+			fmt.Println(empty)
+			return
+		}
+		printTasks(list, view)
+	}
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func printCSV(header []string, rows [][]string) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write(header)
+	w.WriteAll(rows)
+}
+
+var taskCSVHeader = []string{"id", "status", "priority", "due", "project", "tags", "parent", "blocked_by", "repeat", "description", "created", "updated"}
+
+func taskCSVRow(task tasks.Task) []string {
+	var due, parent, repeat string
+	if task.DueDate != nil {
+		due = task.DueDate.Format(tasks.DateLayout)
+	}
+	if task.ParentID != 0 {
+		parent = strconv.Itoa(task.ParentID)
+	}
+	if task.Recurrence != nil {
+		repeat = task.Recurrence.String()
+	}
+	return []string{
+		strconv.Itoa(task.ID),
+		string(task.Status),
+		string(task.Priority),
+		due,
+		task.Project,
+		strings.Join(task.Tags, ","),
+		parent,
+		tasks.JoinIDs(task.BlockedBy),
+		repeat,
+		task.Description,
+		task.CreatedAt.Format(time.RFC3339),
+		task.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"task-cli/tasks"
+)
+
+// reminder is one alert about an unfinished task coming due.
+type reminder struct {
+	Task tasks.Task `json:"task"`
+	// Offset is how long before the deadline the reminder was set for.
+	Offset   time.Duration `json:"-"`
+	Before   string        `json:"before"`
+	Deadline time.Time     `json:"deadline"`
+	Message  string        `json:"message"`
+}
+
+// notifier delivers reminders. name keeps apart what each notifier has
+// already delivered, so one failing notifier is retried without repeating
+// the others.
+type notifier interface {
+	name() string
+	notify(r reminder) error
+}
+
+// logNotifier prints reminders, by default on stdout.
+type logNotifier struct {
+	w io.Writer
+}
+
+func (n logNotifier) name() string { return "log" }
+
+func (n logNotifier) notify(r reminder) error {
+	_, err := fmt.Fprintf(n.w, "%s Reminder: %s\n", time.Now().Format(timeLayout), r.Message)
+	return err
+}
+
+// commandNotifier runs a shell command for each reminder, with the details
+// in TASK_* environment variables, as in
+// notify-send "Task due" "$TASK_MESSAGE".
+type commandNotifier struct {
+	command string
+}
+
+func (n commandNotifier) name() string { return "exec " + n.command }
+
+func (n commandNotifier) notify(r reminder) error {
+	cmd := exec.Command("sh", "-c", n.command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", n.command)
+	}
+	due := ""
+	if r.Task.DueDate != nil {
+		due = r.Task.DueDate.Format(tasks.DateLayout)
+	}
+	cmd.Env = append(os.Environ(),
+		"TASK_ID="+strconv.Itoa(r.Task.ID),
+		"TASK_UID="+r.Task.UID,
+		"TASK_DESCRIPTION="+r.Task.Description,
+		"TASK_DUE="+due,
+		"TASK_BEFORE="+r.Before,
+		"TASK_MESSAGE="+r.Message,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("running %q: %w: %s", n.command, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// webhookNotifier posts each reminder as JSON to a URL.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n webhookNotifier) name() string { return "webhook " + n.url }
+
+func (n webhookNotifier) notify(r reminder) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("posting to %s: %s", n.url, resp.Status)
+	}
+	return nil
+}
+
+// parseOffsets reads how long before the deadline reminders fire, as a
+// comma separated list of durations such as 1w,1d,2h,30m. 0 fires when the
+// task becomes overdue.
+func parseOffsets(value string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		var (
+			offset time.Duration
+			err    error
+		)
+		switch {
+		case strings.HasSuffix(item, "d") || strings.HasSuffix(item, "w"):
+			n, convErr := strconv.Atoi(item[:len(item)-1])
+			offset, err = time.Duration(n)*24*time.Hour, convErr
+			if strings.HasSuffix(item, "w") {
+				offset *= 7
+			}
+		default:
+			offset, err = time.ParseDuration(item)
+		}
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid reminder offset %q (use durations such as 1d, 2h or 30m)", item)
+		}
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets, nil
+}
+
+// formatOffset writes an offset back the way parseOffsets reads it.
+func formatOffset(offset time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case offset == 0:
+		return "0"
+	case offset%(7*day) == 0:
+		return fmt.Sprintf("%dw", offset/(7*day))
+	case offset%day == 0:
+		return fmt.Sprintf("%dd", offset/day)
+	}
+	s := offset.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// dueDeadline is the end of the due day: a task only becomes overdue once
+// its due day has passed.
+func dueDeadline(task tasks.Task) time.Time {
+	return task.DueDate.AddDate(0, 0, 1)
+}
+
+// dueMessage describes when the task is due, as seen at now.
+func dueMessage(task tasks.Task, now time.Time) string {
+	due := task.DueDate.Format(tasks.DateLayout)
+	days := int(task.DueDate.Sub(tasks.StartOfDay(now)).Round(24*time.Hour) / (24 * time.Hour))
+	when := fmt.Sprintf("is due in %d days (%s)", days, due)
+	switch {
+	case days < 0:
+		when = "is overdue (due " + due + ")"
+	case days == 0:
+		when = "is due today"
+	case days == 1:
+		when = "is due tomorrow (" + due + ")"
+	}
+	return fmt.Sprintf("task %d %q %s", task.ID, task.Description, when)
+}
+
+// reminderState remembers the reminders already delivered, in a file next
+// to the task file such as .tasks.reminders.json.
+type reminderState struct {
+	path string
+	Sent map[string]time.Time `json:"sent"`
+}
+
+func loadReminderState(location storeLocation) (*reminderState, error) {
+	state := &reminderState{path: siblingPath(location.path(), ".reminders.json"), Sent: map[string]time.Time{}}
+	data, err := os.ReadFile(state.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", state.path, err)
+	}
+	if state.Sent == nil {
+		state.Sent = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+func (s *reminderState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tasks.WriteFileAtomic(s.path, data)
+}
+
+// reminderKey names one reminder for one notifier. It holds the due date
+// so that moving the due date arms the reminders again.
+func reminderKey(n notifier, task tasks.Task, offset time.Duration) string {
+	id := task.UID
+	if id == "" {
+		id = strconv.Itoa(task.ID)
+	}
+	return strings.Join([]string{n.name(), id, task.DueDate.Format(tasks.DateLayout), formatOffset(offset)}, " ")
+}
+
+// dueReminders returns the reminders that have come due at now. Only the
+// latest reminder of a task fires: after the daemon was stopped for a
+// while, a task now overdue does not also get its one-day reminder.
+// passed lists every offset whose time has come, to be marked as sent.
+func dueReminders(tasks []tasks.Task, offsets []time.Duration, now time.Time) (due []reminder, passed map[int][]time.Duration) {
+	passed = map[int][]time.Duration{}
+	for _, task := range tasks {
+		if task.DueDate == nil || workflow.IsTerminal(task.Status) {
+			continue
+		}
+		deadline := dueDeadline(task)
+		for _, offset := range offsets {
+			if !now.Before(deadline.Add(-offset)) {
+				passed[task.ID] = append(passed[task.ID], offset)
+			}
+		}
+		if n := len(passed[task.ID]); n > 0 {
+			offset := passed[task.ID][n-1]
+			due = append(due, reminder{
+				Task: task, Offset: offset, Before: formatOffset(offset), Deadline: deadline,
+				Message: dueMessage(task, now),
+			})
+		}
+	}
+	return due, passed
+}
+
+// sendReminders delivers the reminders due at now that a notifier has not
+// delivered yet and records them. It returns the number delivered.
+func sendReminders(store tasks.Store, state *reminderState, offsets []time.Duration, notifiers []notifier, now time.Time) (int, error) {
+	list, err := store.List(tasks.Filter{})
+	if err != nil {
+		return 0, storageError(err)
+	}
+	due, passed := dueReminders(list, offsets, now)
+
+	sent := 0
+	live := map[string]bool{}
+	for _, r := range due {
+		for _, n := range notifiers {
+			for _, offset := range passed[r.Task.ID] {
+				live[reminderKey(n, r.Task, offset)] = true
+			}
+			if _, ok := state.Sent[reminderKey(n, r.Task, r.Offset)]; ok {
+				continue
+			}
+			if err := n.notify(r); err != nil {
+				warn("%s: %v", n.name(), err)
+				continue
+			}
+			sent++
+			for _, offset := range passed[r.Task.ID] {
+				state.Sent[reminderKey(n, r.Task, offset)] = now
+			}
+		}
+	}
+	// Reminders of tasks finished, deleted or moved to another due date
+	// are forgotten.
+	changed := sent > 0
+	for key := range state.Sent {
+		if !live[key] {
+			delete(state.Sent, key)
+			changed = true
+		}
+	}
+	if changed {
+		if err := state.save(); err != nil {
+			return sent, storageError(err)
+		}
+	}
+	return sent, nil
+}
+
+// runDaemon checks for due reminders every interval until interrupted, or
+// once.
+func runDaemon(store tasks.Store, location storeLocation, offsets []time.Duration, notifiers []notifier, interval time.Duration, once bool) error {
+	state, err := loadReminderState(location)
+	if err != nil {
+		return storageError(err)
+	}
+	if once {
+		_, err := sendReminders(store, state, offsets, notifiers, time.Now())
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	before := make([]string, len(offsets))
+	for i, offset := range offsets {
+		before[i] = formatOffset(offset)
+	}
+	out.info("Watching %s for reminders %s before the due date (Ctrl-C to stop)", location.path(), strings.Join(before, ", "))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// A store that cannot be read, as during a sync, is tried again
+		// at the next tick.
+		if _, err := sendReminders(store, state, offsets, notifiers, time.Now()); err != nil {
+			warn("%v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
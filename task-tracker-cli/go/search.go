@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+
+	"task-cli/tasks"
+)
+
+// searchIndexVersion changes whenever searchIndex does, so older index files
+// are rebuilt instead of misread.
+const searchIndexVersion = 2
+
+// searchIndex is kept in a file next to the store. It holds a trimmed copy
+// of every task and a trigram index over the searchable text, so a search
+// neither scores every task nor reads more from the store than the tasks
+// it prints. It is rebuilt on the first search after the store file
+// changes.
+type searchIndex struct {
+	Version int `json:"version"`
+	// Stamp identifies the version of the store the index was built from.
+	Stamp string       `json:"stamp"`
+	Tasks []tasks.Task `json:"tasks"`
+	// Trigrams maps each trigram of the searchable text to the positions
+	// of the tasks containing it.
+	Trigrams map[string][]int `json:"trigrams"`
+}
+
+// searchHit is the ID of a task matching a query, with the description
+// runes to highlight. The task itself is read from the store, as the index
+// only holds a trimmed copy.
+type searchHit struct {
+	id        int
+	score     float64
+	highlight []int
+}
+
+// storeStamp describes the current version of the store files. Any write
+// changes the size or modification time of the file, or of the SQLite
+// write-ahead log.
+func storeStamp(location storeLocation) string {
+	var parts []string
+	for _, path := range []string{location.jsonPath, location.dbPath, location.dbPath + "-wal"} {
+		if info, err := os.Stat(path); err == nil {
+			parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// loadSearchIndex returns an up-to-date index, rebuilding and saving it
+// when the store has changed since it was written.
+func loadSearchIndex(store tasks.Store, location storeLocation) (*searchIndex, error) {
+	path := siblingPath(location.path(), ".index")
+	stamp := storeStamp(location)
+
+	if data, err := os.ReadFile(path); err == nil {
+		var index searchIndex
+		if json.Unmarshal(data, &index) == nil && index.Version == searchIndexVersion && index.Stamp == stamp {
+			return &index, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	list, err := store.List(tasks.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	index := buildSearchIndex(list)
+	index.Stamp = stamp
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	// The index is only a cache, so failing to save it must not fail the
+	// search.
+	if err := tasks.WriteFileAtomic(path, data); err != nil {
+		warn("Could not save the search index: %v", err)
+	}
+	return index, nil
+}
+
+func buildSearchIndex(list []tasks.Task) *searchIndex {
+	index := &searchIndex{Version: searchIndexVersion, Tasks: make([]tasks.Task, len(list)), Trigrams: map[string][]int{}}
+	for i, task := range list {
+		// Only what search shows and filters on is kept.
+		index.Tasks[i] = tasks.Task{
+			ID:          task.ID,
+			Description: task.Description,
+			Status:      task.Status,
+			Priority:    task.Priority,
+			DueDate:     task.DueDate,
+			Project:     task.Project,
+			Tags:        task.Tags,
+			Notes:       task.Notes,
+			CreatedAt:   task.CreatedAt,
+		}
+		seen := map[string]bool{}
+		for _, field := range searchFields(task) {
+			for _, trigram := range trigrams(field.text) {
+				if !seen[trigram] {
+					seen[trigram] = true
+					index.Trigrams[trigram] = append(index.Trigrams[trigram], i)
+				}
+			}
+		}
+	}
+	return index
+}
+
+// searchField is one piece of searchable text and how much a match in it
+// counts.
+type searchField struct {
+	text   string
+	weight float64
+	// description marks the field whose matches are highlighted.
+	description bool
+}
+
+func searchFields(task tasks.Task) []searchField {
+	fields := []searchField{{text: strings.ToLower(task.Description), weight: 1, description: true}}
+	for _, tag := range task.Tags {
+		fields = append(fields, searchField{text: strings.ToLower(tag), weight: 0.8})
+	}
+	if task.Project != "" {
+		fields = append(fields, searchField{text: strings.ToLower(task.Project), weight: 0.6})
+	}
+	for _, note := range task.Notes {
+		fields = append(fields, searchField{text: strings.ToLower(note.Text), weight: 0.5})
+	}
+	return fields
+}
+
+func trigrams(text string) []string {
+	runes := []rune(text)
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+3]))
+	}
+	return grams
+}
+
+// search ranks the tasks matching filter by how well they match every word
+// of query, best first. Tasks sharing trigrams with the query are scored
+// first; the rest are only scanned, for abbreviations such as "dcs" for
+// "docs", when that leaves fewer than limit hits.
+func (index *searchIndex) search(query string, filter tasks.Filter, limit int) []searchHit {
+	words := strings.Fields(strings.ToLower(query))
+	candidates := index.candidates(words)
+
+	scored := map[int]bool{}
+	done := map[int]bool{}
+	var hits []searchHit
+	score := func(i int) {
+		scored[i] = true
+		task := index.Tasks[i]
+		if !filter.Match(task) {
+			return
+		}
+		hit := searchHit{id: task.ID}
+		for _, word := range words {
+			score, positions := matchWord(word, searchFields(task))
+			if score == 0 {
+				return
+			}
+			hit.score += score
+			hit.highlight = append(hit.highlight, positions...)
+		}
+		done[task.ID] = workflow.IsTerminal(task.Status)
+		hits = append(hits, hit)
+	}
+	for _, i := range candidates {
+		score(i)
+	}
+	if limit == 0 || len(hits) < limit {
+		for i := range index.Tasks {
+			if !scored[i] {
+				score(i)
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		// Open tasks first, then the most recent.
+		if done[hits[i].id] != done[hits[j].id] {
+			return !done[hits[i].id]
+		}
+		return hits[i].id > hits[j].id
+	})
+	return hits
+}
+
+// candidates returns the tasks sharing at least one trigram with every
+// word of three letters or more. Shorter words cannot use the index.
+func (index *searchIndex) candidates(words []string) []int {
+	var result map[int]bool
+	for _, word := range words {
+		grams := trigrams(word)
+		if len(grams) == 0 {
+			continue
+		}
+		found := map[int]bool{}
+		for _, gram := range grams {
+			for _, i := range index.Trigrams[gram] {
+				if result == nil || result[i] {
+					found[i] = true
+				}
+			}
+		}
+		result = found
+	}
+
+	var positions []int
+	if result == nil {
+		for i := range index.Tasks {
+			positions = append(positions, i)
+		}
+		return positions
+	}
+	for i := range result {
+		positions = append(positions, i)
+	}
+	sort.Ints(positions)
+	return positions
+}
+
+// matchWord scores the best match of word in any field and returns the
+// description runes it covers. An exact substring scores highest, then the
+// letters of word appearing in order, then a word sharing most of its
+// trigrams, which forgives typos.
+func matchWord(word string, fields []searchField) (float64, []int) {
+	var (
+		best      float64
+		positions []int
+	)
+	for _, field := range fields {
+		score, matched := matchField(word, field.text)
+		score *= field.weight
+		if score > best {
+			best = score
+			positions = nil
+			if field.description {
+				positions = matched
+			}
+		}
+	}
+	return best, positions
+}
+
+func matchField(word, text string) (float64, []int) {
+	runes, target := []rune(text), []rune(word)
+	if i := strings.Index(text, word); i >= 0 {
+		start := len([]rune(text[:i]))
+		score := 1.0
+		if start == 0 || !isWordRune(runes[start-1]) {
+			score += 0.5
+		}
+		if end := start + len(target); end == len(runes) || !isWordRune(runes[end]) {
+			score += 0.3
+		}
+		return score, span(start, start+len(target))
+	}
+
+	if positions := subsequence(target, runes); positions != nil {
+		spread := positions[len(positions)-1] - positions[0] + 1
+		if spread <= 3*len(target) {
+			return 0.8 * float64(len(target)) / float64(spread), positions
+		}
+	}
+
+	grams := trigrams(word)
+	if len(grams) < 2 {
+		return 0, nil
+	}
+	// Compare against each word of the text so the highlight lands on
+	// the closest one.
+	var (
+		best      float64
+		positions []int
+	)
+	for start := 0; start < len(runes); {
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if end > start {
+			shared := 0
+			candidate := string(runes[start:end])
+			for _, gram := range grams {
+				if strings.Contains(candidate, gram) {
+					shared++
+				}
+			}
+			if similarity := float64(shared) / float64(len(grams)); similarity >= 0.4 && similarity > best {
+				best, positions = similarity, span(start, end)
+			}
+		}
+		start = end + 1
+	}
+	return 0.5 * best, positions
+}
+
+// subsequence returns the positions of target's runes appearing in order
+// in text, preferring the tightest run at the end, or nil.
+func subsequence(target, text []rune) []int {
+	positions := make([]int, 0, len(target))
+	for i := 0; i < len(text) && len(positions) < len(target); i++ {
+		if text[i] == target[len(positions)] {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) < len(target) {
+		return nil
+	}
+	// Walk back from the last match so the first rune is as late as
+	// possible, which keeps the matched letters close together.
+	last := positions[len(positions)-1]
+	for j, i := len(target)-1, last; j >= 0; i-- {
+		if text[i] == target[j] {
+			positions[j] = i
+			j--
+		}
+	}
+	return positions
+}
+
+func span(start, end int) []int {
+	positions := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		positions = append(positions, i)
+	}
+	return positions
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// splitSearchArgs separates filter terms, such as status:todo or a bare
+// status, from the words to search for.
+func splitSearchArgs(args []string) (string, []string) {
+	var query, filter []string
+	for _, arg := range args {
+		for _, word := range strings.Fields(arg) {
+			if isFilterTerm(word) {
+				filter = append(filter, word)
+			} else {
+				query = append(query, word)
+			}
+		}
+	}
+	return strings.Join(query, " "), filter
+}
+
+func searchTasks(store tasks.Store, location storeLocation, query string, filter tasks.Filter, limit int) error {
+	index, err := loadSearchIndex(store, location)
+	if err != nil {
+		return storageError(err)
+	}
+	hits := index.search(query, filter, limit)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	list := make([]tasks.Task, 0, len(hits))
+	view := taskView{}
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		view.highlight = map[int][]int{}
+	}
+	for _, hit := range hits {
+		task, err := store.Get(hit.id)
+		if errors.Is(err, tasks.ErrNotFound) {
+			// Deleted since the index was checked.
+			continue
+		} else if err != nil {
+			return storageError(err)
+		}
+		list = append(list, task)
+		if view.highlight != nil {
+			view.highlight[hit.id] = hit.highlight
+		}
+	}
+	out.tasks(list, view, fmt.Sprintf("No tasks match %q.", query))
+	return nil
+}
+
+// highlightRunes emphasizes the runes of s at positions for a terminal.
+func highlightRunes(s string, positions []int) string {
+	if len(positions) == 0 {
+		return s
+	}
+	marked := map[int]bool{}
+	for _, p := range positions {
+		marked[p] = true
+	}
+	var b strings.Builder
+	inside := false
+	for i, r := range []rune(s) {
+		if marked[i] != inside {
+			inside = marked[i]
+			if inside {
+				b.WriteString("\x1b[1;33m")
+			} else {
+				b.WriteString("\x1b[0m")
+			}
+		}
+		b.WriteRune(r)
+	}
+	if inside {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
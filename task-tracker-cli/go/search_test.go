@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"task-cli/tasks"
+)
+
+func TestSearchRanksAndForgivesTypos(t *testing.T) {
+	index := buildSearchIndex([]tasks.Task{
+		{ID: 1, Description: "Write release notes", Status: tasks.StatusTaskDone, Tags: []string{"docs"}},
+		{ID: 2, Description: "Fix login bug on mobile", Status: tasks.StatusTaskTodo, Project: "web", Notes: []tasks.Note{{Text: "Happens once the session expires"}}},
+		{ID: 3, Description: "Document the API", Status: tasks.StatusTaskTodo, Tags: []string{"docs", "api"}},
+		{ID: 4, Description: "Release 2.0", Status: tasks.StatusTaskTodo},
+	})
+
+	tests := []struct {
+		query  string
+		filter string
+		want   []int
+	}{
+		{"release", "", []int{4, 1}},
+		{"docs", "", []int{3, 1}},
+		{"docs", "status:todo", []int{3}},
+		{"loign", "", []int{2}},
+		{"dcmnt", "", []int{3}},
+		{"web bug", "", []int{2}},
+		{"session", "", []int{2}},
+		{"nothing", "", nil},
+	}
+	for _, tt := range tests {
+		filter, err := tasks.ParseFilter([]string{tt.filter}, workflow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, hit := range index.search(tt.query, filter, 20) {
+			got = append(got, hit.id)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("search %q %s = %v, want %v", tt.query, tt.filter, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("search %q %s = %v, want %v", tt.query, tt.filter, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchPrintsWholeTasks(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "add", "gather figures")
+	runCLI(t, dir, "add", "write report", "--blocked-by", "1", "--repeat", "weekly")
+
+	stdout, _, _ := runCLIStatus(t, dir, "--output", "json", "search", "report")
+	var list []tasks.Task
+	if err := json.Unmarshal([]byte(stdout), &list); err != nil {
+		t.Fatalf("search --output json is not valid JSON: %v\n%s", err, stdout)
+	}
+	if len(list) != 1 {
+		t.Fatalf("search found %d tasks, want 1:\n%s", len(list), stdout)
+	}
+	task := list[0]
+	if task.UID == "" || task.UpdatedAt.IsZero() || !slices.Equal(task.BlockedBy, []int{1}) || task.Recurrence == nil {
+		t.Errorf("search printed %+v, want the task as stored", task)
+	}
+}
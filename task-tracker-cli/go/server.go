@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task-cli/tasks"
+)
+
+// errPrecondition is returned when an If-Match header no longer matches the
+// task, because someone else changed it since the client read it.
+var errPrecondition = errors.New("task has changed since it was read")
+
+// taskServer serves the task store over HTTP. Every write reads, checks
+// and saves the task in one store transaction, which CLI commands changing
+// the same store wait for, so neither can overwrite the other's change.
+// Clients that send If-Match with the ETag of a task are also protected
+// against overwriting a change they have not seen: the ETag is compared
+// inside that transaction.
+type taskServer struct {
+	store *journalStore
+	// mu keeps each request's writes in a journal batch of their own, as
+	// the journal store is shared by all requests.
+	mu sync.Mutex
+}
+
+func (s *taskServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", s.list)
+	mux.HandleFunc("POST /tasks", s.add)
+	mux.HandleFunc("GET /tasks/{id}", s.get)
+	mux.HandleFunc("PATCH /tasks/{id}", s.update)
+	mux.HandleFunc("DELETE /tasks/{id}", s.delete)
+	mux.HandleFunc("POST /tasks/{id}/status", s.status)
+	return mux
+}
+
+func serveTasks(store *journalStore, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           (&taskServer{store: store}).routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	out.info("Serving tasks on http://%s (Ctrl-C to stop)", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// taskRequest is the body of POST /tasks and PATCH /tasks/{id}. Fields left
+// out are not changed; an empty string clears priority, dueDate, project
+// and repeat, and tags and blockedBy replace the whole list.
+type taskRequest struct {
+	Description *string  `json:"description"`
+	Priority    *string  `json:"priority"`
+	DueDate     *string  `json:"dueDate"`
+	Project     *string  `json:"project"`
+	Tags        []string `json:"tags"`
+	ParentID    *int     `json:"parentId"`
+	BlockedBy   []int    `json:"blockedBy"`
+	Repeat      *string  `json:"repeat"`
+}
+
+// update turns the request into the changes it makes to current.
+func (r taskRequest) update(current tasks.Task) (tasks.Update, error) {
+	var update tasks.Update
+	if r.Description != nil {
+		if strings.TrimSpace(*r.Description) == "" {
+			return update, invalidInput("description cannot be empty")
+		}
+		update.Description = r.Description
+	}
+	if r.Priority != nil {
+		value := *r.Priority
+		if value == "" {
+			value = "none"
+		}
+		p, err := tasks.ParsePriority(value)
+		if err != nil {
+			return update, invalidInput("%w", err)
+		}
+		update.Priority = &p
+	}
+	if r.DueDate != nil {
+		if *r.DueDate == "" {
+			update.ClearDue = true
+		} else {
+			due, err := parseDueDate(*r.DueDate)
+			if err != nil {
+				return update, invalidInput("%w", err)
+			}
+			update.DueDate = &due
+		}
+	}
+	update.Project = r.Project
+	// A new list removes only the entries it leaves out, as Apply adds
+	// before it removes.
+	if r.Tags != nil {
+		update.RemoveTags = slices.DeleteFunc(slices.Clone(current.Tags), func(tag string) bool {
+			return slices.ContainsFunc(r.Tags, func(kept string) bool { return strings.EqualFold(kept, tag) })
+		})
+		update.AddTags = r.Tags
+	}
+	update.ParentID = r.ParentID
+	if r.BlockedBy != nil {
+		update.Unblock = slices.DeleteFunc(slices.Clone(current.BlockedBy), func(id int) bool {
+			return slices.Contains(r.BlockedBy, id)
+		})
+		update.AddBlockers = r.BlockedBy
+	}
+	if r.Repeat != nil {
+		if *r.Repeat == "" {
+			update.NoRepeat = true
+		} else {
+			due := update.DueDate
+			if due == nil && !update.ClearDue {
+				due = current.DueDate
+			}
+			recurrence, err := tasks.ParseRecurrence(*r.Repeat, due)
+			if err != nil {
+				return update, invalidInput("%w", err)
+			}
+			update.Recurrence = recurrence
+		}
+	}
+	return update, nil
+}
+
+func (s *taskServer) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := tasks.ParseFilter(query["filter"], workflow)
+	if err != nil {
+		writeError(w, invalidInput("%w", err))
+		return
+	}
+	found, err := s.store.List(filter)
+	if err != nil {
+		writeError(w, storageError(err))
+		return
+	}
+	if found == nil {
+		found = []tasks.Task{}
+	}
+	switch query.Get("sort") {
+	case "":
+	case "priority":
+		sortByPriority(found)
+	case "due":
+		sortByDueDate(found)
+	default:
+		writeError(w, invalidInput("invalid sort order (use priority or due)"))
+		return
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+func (s *taskServer) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	task, err := s.store.Get(id)
+	if err != nil {
+		writeError(w, storeError(id, err))
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *taskServer) add(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Description == nil {
+		writeError(w, invalidInput("description required"))
+		return
+	}
+	update, err := req.update(tasks.Task{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var task tasks.Task
+	update.Apply(&task)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.startBatch()
+	task, err = tracker(s.store).Add(task)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/tasks/%d", task.ID))
+	writeTask(w, http.StatusCreated, task)
+}
+
+func (s *taskServer) update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req taskRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.startBatch()
+	task, err := s.tracker(r).EditWith(id, req.update)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *taskServer) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.startBatch()
+	deleted, err := s.tracker(r).Remove(id, cascade)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleted)
+}
+
+// status changes the status of a task with a body such as
+// {"status": "done", "force": false} and returns the task along with any
+// follow-up a recurring task scheduled.
+func (s *taskServer) status(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req struct {
+		Status tasks.StatusTask `json:"status"`
+		Force  bool             `json:"force"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if !isKnownStatus(string(req.Status)) {
+		writeError(w, invalidInput("invalid status %q", req.Status))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.startBatch()
+	change, err := s.tracker(r).SetStatus(id, req.Status, req.Force)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", taskETag(change.Task))
+	writeJSON(w, http.StatusOK, struct {
+		Task      tasks.Task   `json:"task"`
+		FollowUps []tasks.Task `json:"followUps,omitempty"`
+	}{change.Task, change.FollowUps})
+}
+
+// tracker returns the tracker that makes the change r asks for. When r
+// carries If-Match, it refuses to change a task that no longer has that
+// ETag.
+func (s *taskServer) tracker(r *http.Request) tasks.Tracker {
+	tr := tracker(s.store)
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" {
+		tr.Check = func(task tasks.Task) error {
+			if taskETag(task) != match {
+				return errPrecondition
+			}
+			return nil
+		}
+	}
+	return tr
+}
+
+// taskETag changes whenever the task is saved.
+func taskETag(task tasks.Task) string {
+	return fmt.Sprintf("\"%d-%d\"", task.ID, task.UpdatedAt.UnixNano())
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, invalidInput("invalid ID %q", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+func storeError(id int, err error) error {
+	if errors.Is(err, tasks.ErrNotFound) {
+		return notFound(id)
+	}
+	return storageError(err)
+}
+
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return invalidInput("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeTask(w http.ResponseWriter, code int, task tasks.Task) {
+	w.Header().Set("ETag", taskETag(task))
+	writeJSON(w, code, task)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError answers with the HTTP status matching the CLI exit code of
+// err.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusConflict
+	switch {
+	case errors.Is(err, errPrecondition):
+		code = http.StatusPreconditionFailed
+	case exitCode(err) == exitInvalid:
+		code = http.StatusBadRequest
+	case exitCode(err) == exitNotFound:
+		code = http.StatusNotFound
+	case exitCode(err) == exitStorage:
+		code = http.StatusInternalServerError
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"task-cli/tasks"
+)
+
+func TestServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), projectFileName)
+	store := newJournalStore(tasks.NewJSONStore(path), path)
+	srv := httptest.NewServer((&taskServer{store: store}).routes())
+	defer srv.Close()
+
+	do := func(method, url, body string, header ...string) (*http.Response, map[string]any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]any
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp, decoded
+	}
+
+	resp, task := do("POST", "/tasks", `{"description": "from the api", "priority": "high", "tags": ["web"]}`)
+	if resp.StatusCode != http.StatusCreated || task["id"] != 1.0 || task["priority"] != "high" {
+		t.Fatalf("POST /tasks = %d %v", resp.StatusCode, task)
+	}
+	etag := resp.Header.Get("ETag")
+
+	// A change made behind the client's back, as the CLI would.
+	if _, err := tracker(store).Edit(1, tasks.Update{Description: new(string)}); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := do("PATCH", "/tasks/1", `{"project": "site"}`, "If-Match", etag); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with a stale If-Match = %d, want 412", resp.StatusCode)
+	}
+	if resp, task := do("PATCH", "/tasks/1", `{"description": "renamed", "tags": []}`); resp.StatusCode != http.StatusOK || task["description"] != "renamed" || task["tags"] != nil {
+		t.Errorf("PATCH /tasks/1 = %d %v", resp.StatusCode, task)
+	}
+
+	if resp, body := do("POST", "/tasks/1/status", `{"status": "done"}`); resp.StatusCode != http.StatusOK || body["task"].(map[string]any)["status"] != "done" {
+		t.Errorf("POST /tasks/1/status = %d %v", resp.StatusCode, body)
+	}
+	if resp, _ := do("POST", "/tasks/1/status", `{"status": "finished"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid status = %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := do("GET", "/tasks/7", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /tasks/7 = %d, want 404", resp.StatusCode)
+	}
+	if resp, _ := do("DELETE", "/tasks/1", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("DELETE /tasks/1 = %d", resp.StatusCode)
+	}
+	if list, _ := store.List(tasks.Filter{}); len(list) != 0 {
+		t.Errorf("%d task(s) left after DELETE", len(list))
+	}
+}
+
+func TestServerAndCLIWritesDoNotOverwriteEachOther(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "shared")
+
+	path := filepath.Join(dir, projectFileName)
+	store := newJournalStore(tasks.NewJSONStore(path), path)
+	srv := httptest.NewServer((&taskServer{store: store}).routes())
+	defer srv.Close()
+
+	// Clients of the server keep renaming the task, each time with the
+	// ETag they just read, while CLI processes tag it, and until each has
+	// renamed it at least once.
+	done := make(chan struct{})
+	var (
+		mu   sync.Mutex
+		sent = map[string]string{}
+		wg   sync.WaitGroup
+	)
+	for client := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			renamed := false
+			for n := 0; ; n++ {
+				select {
+				case <-done:
+					if renamed {
+						return
+					}
+				default:
+				}
+				resp, err := http.Get(srv.URL + "/tasks/1")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+				etag := resp.Header.Get("ETag")
+				description := fmt.Sprintf("server %d.%d", client, n)
+				req, _ := http.NewRequest("PATCH", srv.URL+"/tasks/1", strings.NewReader(`{"description": "`+description+`"}`))
+				req.Header.Set("If-Match", etag)
+				resp, err = http.DefaultClient.Do(req)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPreconditionFailed {
+					t.Errorf("PATCH /tasks/1 = %d", resp.StatusCode)
+					return
+				}
+				if resp.StatusCode == http.StatusOK {
+					renamed = true
+					mu.Lock()
+					sent[description] = etag
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	const processes = 10
+	runConcurrently(processes, func(i int) {
+		runCLI(t, dir, "update", "1", "--tag", fmt.Sprintf("c%d", i))
+	})
+	close(done)
+	wg.Wait()
+
+	if tags := readTaskFile(t, dir)[0].Tags; len(tags) != processes {
+		t.Errorf("task 1 has %d of %d tags added by the CLI: %v", len(tags), processes, tags)
+	}
+	entries, err := store.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.After == nil {
+			continue
+		}
+		etag, ok := sent[entry.After.Description]
+		if ok && entry.Before != nil && entry.Before.Description != entry.After.Description && taskETag(*entry.Before) != etag {
+			t.Errorf("PATCH to %q with If-Match %s replaced task 1 as of %s, a change the client had not seen", entry.After.Description, etag, taskETag(*entry.Before))
+		}
+	}
+}
+
+func TestServerReplacesLists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), projectFileName)
+	store := newJournalStore(tasks.NewJSONStore(path), path)
+	srv := httptest.NewServer((&taskServer{store: store}).routes())
+	defer srv.Close()
+
+	send := func(method, url, body string) tasks.Task {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+url, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var task tasks.Task
+		if err := json.NewDecoder(resp.Body).Decode(&task); err != nil || resp.StatusCode >= 300 {
+			t.Fatalf("%s %s = %d (%v)", method, url, resp.StatusCode, err)
+		}
+		return task
+	}
+	for _, description := range []string{"first", "second", "third"} {
+		send("POST", "/tasks", `{"description": "`+description+`"}`)
+	}
+	send("POST", "/tasks", `{"description": "replaced", "tags": ["x", "y"], "blockedBy": [1, 2]}`)
+
+	// The new lists share x and 2 with the old ones, which must stay.
+	task := send("PATCH", "/tasks/4", `{"tags": ["x", "z"], "blockedBy": [2, 3]}`)
+	if !slices.Equal(task.Tags, []string{"x", "z"}) || !slices.Equal(task.BlockedBy, []int{2, 3}) {
+		t.Errorf("after replacing the lists, tags = %q and blockedBy = %v; want [x z] and [2 3]", task.Tags, task.BlockedBy)
+	}
+	task = send("PATCH", "/tasks/4", `{"tags": [], "blockedBy": []}`)
+	if len(task.Tags) != 0 || len(task.BlockedBy) != 0 {
+		t.Errorf("after clearing the lists, tags = %q and blockedBy = %v", task.Tags, task.BlockedBy)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"task-cli/tasks"
+)
+
+// burndownHeight is the number of rows of the burndown chart, and
+// burndownWidth the most columns it uses before days are grouped.
+const (
+	burndownHeight = 10
+	burndownWidth  = 60
+)
+
+// completedAt returns when a finished task last reached a terminal status.
+// Tasks finished before the status log existed fall back to UpdatedAt.
+func completedAt(t tasks.Task) (time.Time, bool) {
+	if !workflow.IsTerminal(t.Status) {
+		return time.Time{}, false
+	}
+	for i := len(t.StatusLog) - 1; i >= 0; i-- {
+		if workflow.IsTerminal(t.StatusLog[i].To) {
+			return t.StatusLog[i].At, true
+		}
+	}
+	return t.UpdatedAt, true
+}
+
+// openAt reports whether the task existed and was unfinished at the given
+// time, following the status log when there is one.
+func openAt(t tasks.Task, at time.Time) bool {
+	if t.CreatedAt.After(at) {
+		return false
+	}
+	if len(t.StatusLog) == 0 {
+		done, ok := completedAt(t)
+		return !ok || done.After(at)
+	}
+	status := t.StatusLog[0].From
+	for _, entry := range t.StatusLog {
+		if entry.At.After(at) {
+			break
+		}
+		status = entry.To
+	}
+	return !workflow.IsTerminal(status)
+}
+
+// statsDay is one day of the statistics: tasks created and completed on
+// it and tasks open at its end.
+type statsDay struct {
+	Date      string `json:"date"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+	Open      int    `json:"open"`
+}
+
+// statsWeek totals the days of a week starting on Monday.
+type statsWeek struct {
+	Week      string `json:"week"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+type statusCount struct {
+	Status tasks.StatusTask `json:"status"`
+	Count  int              `json:"count"`
+}
+
+type taskStats struct {
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Days  []statsDay  `json:"days"`
+	Weeks []statsWeek `json:"weeks"`
+	// LeadTime is the average time from creation to completion of the
+	// tasks completed in the period.
+	LeadTime    time.Duration `json:"-"`
+	LeadSeconds int64         `json:"averageLeadTimeSeconds"`
+	Finished    int           `json:"completedInPeriod"`
+	Open        []statusCount `json:"openByStatus"`
+}
+
+// buildStats counts the tasks created and completed on each day from from
+// to to (inclusive), the tasks open at the end of each day and the tasks
+// open now per status.
+func buildStats(list []tasks.Task, from, to time.Time) taskStats {
+	stats := taskStats{From: from.Format(tasks.DateLayout), To: to.Format(tasks.DateLayout)}
+	index := map[string]int{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		open := 0
+		for _, task := range list {
+			if openAt(task, end) {
+				open++
+			}
+		}
+		index[day.Format(tasks.DateLayout)] = len(stats.Days)
+		stats.Days = append(stats.Days, statsDay{Date: day.Format(tasks.DateLayout), Open: open})
+	}
+
+	var lead time.Duration
+	for _, task := range list {
+		if i, ok := index[task.CreatedAt.Format(tasks.DateLayout)]; ok {
+			stats.Days[i].Created++
+		}
+		if done, ok := completedAt(task); ok {
+			if i, ok := index[done.Format(tasks.DateLayout)]; ok {
+				stats.Days[i].Completed++
+				stats.Finished++
+				lead += done.Sub(task.CreatedAt)
+			}
+		}
+	}
+	if stats.Finished > 0 {
+		stats.LeadTime = lead / time.Duration(stats.Finished)
+		stats.LeadSeconds = int64(stats.LeadTime.Seconds())
+	}
+
+	for i, day := range stats.Days {
+		date, _ := time.ParseInLocation(tasks.DateLayout, day.Date, time.Local)
+		week := date.AddDate(0, 0, -(int(date.Weekday())+6)%7).Format(tasks.DateLayout)
+		if i == 0 || stats.Weeks[len(stats.Weeks)-1].Week != week {
+			stats.Weeks = append(stats.Weeks, statsWeek{Week: week})
+		}
+		last := &stats.Weeks[len(stats.Weeks)-1]
+		last.Created += day.Created
+		last.Completed += day.Completed
+	}
+
+	// Tasks left in a status the workflow no longer has are listed after
+	// the workflow's own statuses.
+	counts := map[tasks.StatusTask]int{}
+	statuses := workflow.Statuses
+	for _, task := range list {
+		if workflow.IsTerminal(task.Status) {
+			continue
+		}
+		if counts[task.Status] == 0 && !workflow.Known(task.Status) {
+			statuses = append(statuses[:len(statuses):len(statuses)], task.Status)
+		}
+		counts[task.Status]++
+	}
+	for _, status := range statuses {
+		if !workflow.IsTerminal(status) {
+			stats.Open = append(stats.Open, statusCount{status, counts[status]})
+		}
+	}
+	return stats
+}
+
+func showStats(store tasks.Store, archive tasks.Store, filter tasks.Filter, from, to time.Time) error {
+	tasks, err := store.List(filter)
+	if err != nil {
+		return storageError(err)
+	}
+	// Archived tasks still count: they were created and finished in
+	// their time.
+	archived, err := archive.List(filter)
+	if err != nil {
+		return storageError(err)
+	}
+	stats := buildStats(append(tasks, archived...), from, to)
+
+	switch out.format {
+	case outputJSON:
+		printJSON(stats)
+
+	case outputCSV:
+		records := make([][]string, len(stats.Days))
+		for i, day := range stats.Days {
+			records[i] = []string{day.Date, fmt.Sprint(day.Created), fmt.Sprint(day.Completed), fmt.Sprint(day.Open)}
+		}
+		printCSV([]string{"date", "created", "completed", "open"}, records)
+
+	default:
+		fmt.Printf("Statistics %s to %s\n\n", stats.From, stats.To)
+		fmt.Printf("%-12s %9s %9s\n", "Week of", "Created", "Completed")
+		fmt.Println("--------------------------------")
+		for _, week := range stats.Weeks {
+			fmt.Printf("%-12s %9d %9d\n", week.Week, week.Created, week.Completed)
+		}
+		fmt.Println()
+		if stats.Finished > 0 {
+			fmt.Printf("Average time to done: %s (%d task(s) completed)\n", formatLeadTime(stats.LeadTime), stats.Finished)
+		} else {
+			fmt.Println("Average time to done: no tasks completed in this period")
+		}
+		var open []string
+		for _, count := range stats.Open {
+			open = append(open, fmt.Sprintf("%s %d", count.Status, count.Count))
+		}
+		fmt.Printf("Open now: %s\n\n", strings.Join(open, ", "))
+		printBurndown(stats.Days)
+	}
+	return nil
+}
+
+// formatLeadTime shows durations of a day or more in days and hours.
+func formatLeadTime(d time.Duration) string {
+	if d < 24*time.Hour {
+		return formatDuration(d)
+	}
+	d = d.Round(time.Hour)
+	return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+}
+
+// printBurndown draws the open tasks at the end of each day as columns of
+// #, with the straight line from the first day's count to zero as dots.
+// Long periods use one column for several days, showing the last of them.
+func printBurndown(days []statsDay) {
+	step := (len(days) + burndownWidth - 1) / burndownWidth
+	var columns []int
+	for i := step - 1; i < len(days)+step-1; i += step {
+		columns = append(columns, days[min(i, len(days)-1)].Open)
+	}
+	top := 0
+	for _, open := range columns {
+		top = max(top, open)
+	}
+	fmt.Println("Burndown (open tasks at the end of each day):")
+	if top == 0 {
+		fmt.Println("No open tasks in this period.")
+		return
+	}
+
+	height := min(top, burndownHeight)
+	// rows scales a count to the number of filled rows.
+	rows := func(n float64) int {
+		return int(n*float64(height)/float64(top) + 0.5)
+	}
+	for row := height; row >= 1; row-- {
+		label := ""
+		switch row {
+		case height:
+			label = fmt.Sprint(top)
+		case (height + 1) / 2:
+			if height > 2 {
+				label = fmt.Sprint(top * row / height)
+			}
+		}
+		var line strings.Builder
+		for i, open := range columns {
+			ideal := float64(columns[0])
+			if len(columns) > 1 {
+				ideal *= 1 - float64(i)/float64(len(columns)-1)
+			}
+			switch {
+			case rows(float64(open)) >= row:
+				line.WriteByte('#')
+			case rows(ideal) == row:
+				line.WriteByte('.')
+			default:
+				line.WriteByte(' ')
+			}
+		}
+		fmt.Printf("%5s | %s\n", label, strings.TrimRight(line.String(), " "))
+	}
+	fmt.Printf("%5s +%s\n", "0", strings.Repeat("-", len(columns)+1))
+	first, last := days[0].Date[5:], days[len(days)-1].Date[5:]
+	gap := max(len(columns)-len(first)-len(last), 1)
+	fmt.Printf("%5s  %s%s%s\n", "", first, strings.Repeat(" ", gap), last)
+	if step > 1 {
+		fmt.Printf("(one column per %d days)\n", step)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"task-cli/tasks"
+)
+
+const (
+	// projectFileName marks a directory tree with its own task list, the
+	// way .git marks a repository.
+	projectFileName = ".tasks.json"
+	// globalDirName holds the global task list under the XDG data dir.
+	globalDirName = "task-cli"
+)
+
+// storeLocation is where one task list lives: a JSON file, or the SQLite
+// database next to it once the list has been migrated.
+type storeLocation struct {
+	jsonPath string
+	dbPath   string
+}
+
+// locationForFile names the store for --file. Either the JSON or the
+// database name may be given; the other one sits next to it.
+func locationForFile(path string) storeLocation {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	if ext == ".db" {
+		return storeLocation{jsonPath: base + ".json", dbPath: path}
+	}
+	return storeLocation{jsonPath: path, dbPath: base + ".db"}
+}
+
+// siblingPath names a file kept next to the store, such as its journal:
+// the store file name with its extension replaced by ext.
+func siblingPath(storePath, ext string) string {
+	return strings.TrimSuffix(storePath, filepath.Ext(storePath)) + ext
+}
+
+func (l storeLocation) exists() bool {
+	for _, path := range []string{l.jsonPath, l.dbPath} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// path is the file currently holding the tasks.
+func (l storeLocation) path() string {
+	if _, err := os.Stat(l.dbPath); err == nil {
+		return l.dbPath
+	}
+	return l.jsonPath
+}
+
+// globalLocation is the store used outside any project, under
+// $XDG_DATA_HOME (by default ~/.local/share).
+func globalLocation() (storeLocation, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return storeLocation{}, fmt.Errorf("finding the global task list: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return locationForFile(filepath.Join(dir, globalDirName, fileName)), nil
+}
+
+// findStore picks the task list a command works on: --file, then --global,
+// then the nearest .tasks.json walking up from the working directory. A
+// tasks.json left in the working directory by older versions still wins
+// over the global list, so existing lists keep working.
+func findStore(global bool, file string) (storeLocation, error) {
+	if file != "" {
+		return locationForFile(file), nil
+	}
+	if global {
+		return globalLocation()
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return storeLocation{}, err
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if location := locationForFile(filepath.Join(dir, projectFileName)); location.exists() {
+			return location, nil
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	if legacy := locationForFile(filepath.Join(cwd, fileName)); legacy.exists() {
+		return legacy, nil
+	}
+	return globalLocation()
+}
+
+// openStore picks the SQLite database once tasks have been migrated into
+// it and the JSON file otherwise, and records every change in the journal.
+func openStore(location storeLocation) (*journalStore, error) {
+	if err := os.MkdirAll(filepath.Dir(location.jsonPath), 0755); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(location.dbPath); err == nil {
+		store, err := tasks.OpenSQLiteStore(location.dbPath)
+		if err != nil {
+			return nil, err
+		}
+		return newJournalStore(store, location.dbPath), nil
+	}
+	return newJournalStore(tasks.NewJSONStore(location.jsonPath), location.jsonPath), nil
+}
+
+// initProject starts a task list for the working directory and everything
+// below it.
+func initProject() error {
+	location := locationForFile(projectFileName)
+	if location.exists() {
+		return fmt.Errorf("%s already exists", location.path())
+	}
+	if err := tasks.WriteFileAtomic(projectFileName, []byte("[]")); err != nil {
+		return storageError(err)
+	}
+	path, _ := filepath.Abs(projectFileName)
+	out.info("Created %s; commands run below this directory now use it", path)
+	return nil
+}
+
+// migrateStore copies every task into the target backend, keeping IDs, and
+// renames the old file so that the new backend is picked up from now on.
+func migrateStore(location storeLocation, to string) error {
+	var (
+		from    tasks.Store
+		target  tasks.Store
+		oldFile string
+		err     error
+	)
+
+	switch to {
+	case "sqlite":
+		if _, err := os.Stat(location.dbPath); err == nil {
+			return fmt.Errorf("%s already exists", location.dbPath)
+		}
+		from, oldFile = tasks.NewJSONStore(location.jsonPath), location.jsonPath
+		target, err = tasks.OpenSQLiteStore(location.dbPath)
+	case "json":
+		if _, err := os.Stat(location.dbPath); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s not found, nothing to migrate", location.dbPath)
+		}
+		from, err = tasks.OpenSQLiteStore(location.dbPath)
+		if err != nil {
+			return storageError(err)
+		}
+		oldFile = location.dbPath
+		target = tasks.NewJSONStore(location.jsonPath)
+	default:
+		return invalidInput("unknown storage backend %q (use sqlite or json)", to)
+	}
+	if err != nil {
+		return storageError(err)
+	}
+
+	list, err := from.List(tasks.Filter{})
+	if err == nil {
+		err = copyTasks(target, list)
+	}
+	// Both stores are closed before the rename so SQLite can fold its
+	// write-ahead log back into the database file.
+	for _, store := range []tasks.Store{from, target} {
+		if closeErr := store.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return storageError(fmt.Errorf("migrating tasks: %w", err))
+	}
+
+	if _, err := os.Stat(oldFile); err == nil {
+		if err := os.Rename(oldFile, oldFile+".migrated"); err != nil {
+			return storageError(err)
+		}
+	}
+	out.info("Migrated %d task(s) to %s", len(list), to)
+	out.changed(list)
+	return nil
+}
+
+// recoverTasks restores backup n of the JSON task file.
+func recoverTasks(s *tasks.JSONStore, n int) error {
+	if n < 1 || n > tasks.BackupCount {
+		return invalidInput("backup number must be between 1 and %d", tasks.BackupCount)
+	}
+	restored, err := s.Recover(n)
+	if err != nil {
+		return storageError(err)
+	}
+	out.info("Restored %d task(s) from %s", len(restored), s.BackupFileName(n))
+	out.changed(restored)
+	return nil
+}
+
+func copyTasks(target tasks.Store, list []tasks.Task) error {
+	existing, err := target.List(tasks.Filter{})
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("target store already holds %d task(s)", len(existing))
+	}
+	_, err = target.Create(list...)
+	return err
+}
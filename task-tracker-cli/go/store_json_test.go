@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"task-cli/tasks"
+)
+
+// TestMain lets the tests re-run this binary as task-cli itself, so that
+// concurrency is exercised across real processes rather than goroutines.
+func TestMain(m *testing.M) {
+	if os.Getenv("TASK_CLI_RUN_MAIN") == "1" {
+		os.Args = append([]string{"task-cli"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// cliEnv keeps the global task list of a test inside its own directory.
+func cliEnv(dir string) []string {
+	return append(os.Environ(), "TASK_CLI_RUN_MAIN=1", "XDG_DATA_HOME="+filepath.Join(dir, "data"))
+}
+
+func runCLI(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = cliEnv(dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Errorf("task-cli %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// runCLIStatus runs task-cli and returns stdout, stderr and the exit code
+// without failing the test, for checking how errors are reported.
+func runCLIStatus(t *testing.T, dir string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = cliEnv(dir)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("task-cli %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+func runConcurrently(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := 1; i <= n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+func readTaskFile(t *testing.T, dir string) []tasks.Task {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, projectFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []tasks.Task
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatalf("task file is not valid JSON: %v", err)
+	}
+	return list
+}
+
+func TestConcurrentProcessesDoNotLoseUpdates(t *testing.T) {
+	const processes = 20
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+
+	runConcurrently(processes, func(i int) {
+		runCLI(t, dir, "add", fmt.Sprintf("task %d", i))
+	})
+
+	list := readTaskFile(t, dir)
+	if len(list) != processes {
+		t.Fatalf("got %d tasks after %d concurrent adds, want %d", len(list), processes, processes)
+	}
+	seen := map[int]bool{}
+	for _, task := range list {
+		if seen[task.ID] {
+			t.Fatalf("duplicate task ID %d", task.ID)
+		}
+		seen[task.ID] = true
+	}
+
+	// Every process changes the same task, so each one's read and write
+	// must not straddle another's.
+	runConcurrently(processes, func(i int) {
+		runCLI(t, dir, "update", "1", "--tag", fmt.Sprintf("t%d", i))
+	})
+	if tags := readTaskFile(t, dir)[0].Tags; len(tags) != processes {
+		t.Errorf("task 1 has %d of %d tags after concurrent updates: %v", len(tags), processes, tags)
+	}
+
+	runConcurrently(processes, func(i int) {
+		runCLI(t, dir, "mark-done", strconv.Itoa(i))
+	})
+
+	for _, task := range readTaskFile(t, dir) {
+		if task.Status != tasks.StatusTaskDone {
+			t.Errorf("task %d has status %q after concurrent mark-done, want done", task.ID, task.Status)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestConcurrentStartsLeaveOneTimerRunning(t *testing.T) {
+	const processes = 10
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	for i := 1; i <= processes; i++ {
+		runCLI(t, dir, "add", fmt.Sprintf("task %d", i))
+	}
+
+	runConcurrently(processes, func(i int) {
+		runCLIStatus(t, dir, "start", strconv.Itoa(i))
+	})
+
+	var running []int
+	for _, task := range readTaskFile(t, dir) {
+		if task.TimerRunning() {
+			running = append(running, task.ID)
+		}
+	}
+	if len(running) != 1 {
+		t.Errorf("timers running on tasks %v after %d concurrent starts, want exactly one", running, processes)
+	}
+}
+
+func TestRecoverRestoresLatestBackup(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, dir, "init")
+	runCLI(t, dir, "add", "first")
+	runCLI(t, dir, "add", "second")
+
+	if err := os.WriteFile(filepath.Join(dir, projectFileName), []byte("[{\"id\": 1,"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, code := runCLIStatus(t, dir, "list")
+	if code != exitStorage || !strings.Contains(stderr, "recover") {
+		t.Errorf("list on a corrupt file exited %d, want %d with a pointer at recover, got:\n%s", code, exitStorage, stderr)
+	}
+
+	runCLI(t, dir, "recover")
+	tasks := readTaskFile(t, dir)
+	if len(tasks) != 1 || tasks[0].Description != "first" {
+		t.Errorf("recovered tasks = %+v, want the state before the last write", tasks)
+	}
+}
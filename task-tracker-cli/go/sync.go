@@ -0,0 +1,655 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-cli/tasks"
+)
+
+const (
+	// syncFileName is the file holding the tasks in the sync repository.
+	syncFileName = "tasks.json"
+	syncBranch   = "main"
+)
+
+// syncConfig is kept next to the task file, such as .tasks.sync.json.
+type syncConfig struct {
+	// Repo is the local git repository the tasks are committed to.
+	Repo string `json:"repo"`
+	// Remote is pulled from and pushed to; usually the path of a bare
+	// repository on a shared drive or another machine.
+	Remote string `json:"remote,omitempty"`
+}
+
+func syncConfigPath(location storeLocation) string {
+	return siblingPath(location.path(), ".sync.json")
+}
+
+// loadSyncConfig reads the sync settings and applies the --repo and
+// --remote flags over them, saving the result when they changed.
+func loadSyncConfig(location storeLocation, repo, remote string) (syncConfig, error) {
+	path := syncConfigPath(location)
+	var config syncConfig
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("reading %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return config, err
+	}
+
+	changed := false
+	for _, setting := range []struct {
+		flag   string
+		target *string
+	}{{repo, &config.Repo}, {remote, &config.Remote}} {
+		if setting.flag == "" {
+			continue
+		}
+		value := setting.flag
+		// Paths are made absolute so sync works from any directory;
+		// URLs are kept as they are.
+		if !strings.Contains(value, "://") {
+			if abs, err := filepath.Abs(value); err == nil {
+				value = abs
+			}
+		}
+		if *setting.target != value {
+			*setting.target, changed = value, true
+		}
+	}
+	if config.Repo == "" {
+		return config, invalidInput("no sync repository configured; run sync --repo DIR [--remote PATH] once")
+	}
+	if changed {
+		data, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return config, err
+		}
+		if err := tasks.WriteFileAtomic(path, data); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// syncRecord is a task as stored in the sync repository, one JSON value
+// per field. Numeric IDs differ between machines, so they are left out and
+// links point at UIDs instead: parent, blockers and next.
+type syncRecord map[string]json.RawMessage
+
+// localOnlyFields are the Task fields replaced by UID links.
+var localOnlyFields = []string{"id", "parentId", "blockedBy", "nextId"}
+
+func toRecord(task tasks.Task, uidOf map[int]string) (syncRecord, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	var record syncRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	for _, field := range localOnlyFields {
+		delete(record, field)
+	}
+	set := func(field string, value any) {
+		record[field], _ = json.Marshal(value)
+	}
+	if uid := uidOf[task.ParentID]; uid != "" {
+		set("parent", uid)
+	}
+	var blockers []string
+	for _, id := range task.BlockedBy {
+		if uid := uidOf[id]; uid != "" {
+			blockers = append(blockers, uid)
+		}
+	}
+	if len(blockers) > 0 {
+		set("blockers", blockers)
+	}
+	if uid := uidOf[task.NextID]; uid != "" {
+		set("next", uid)
+	}
+	return record, nil
+}
+
+// toTask turns a record back into a task with local IDs. Links to tasks
+// that do not exist here are dropped.
+func (record syncRecord) toTask(id int, idOf map[string]int) (tasks.Task, error) {
+	var links struct {
+		Parent   string   `json:"parent"`
+		Blockers []string `json:"blockers"`
+		Next     string   `json:"next"`
+	}
+	fields := syncRecord{}
+	for field, value := range record {
+		switch field {
+		case "parent", "blockers", "next":
+		default:
+			fields[field] = value
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return tasks.Task{}, err
+	}
+	if err := json.Unmarshal(data, &links); err != nil {
+		return tasks.Task{}, err
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return tasks.Task{}, err
+	}
+	var task tasks.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return tasks.Task{}, err
+	}
+
+	task.ID = id
+	task.ParentID = idOf[links.Parent]
+	for _, uid := range links.Blockers {
+		if blocker := idOf[uid]; blocker != 0 {
+			task.BlockedBy = append(task.BlockedBy, blocker)
+		}
+	}
+	task.NextID = idOf[links.Next]
+	return task, nil
+}
+
+func (record syncRecord) uid() string {
+	var uid string
+	json.Unmarshal(record["uid"], &uid)
+	return uid
+}
+
+func (record syncRecord) description() string {
+	var description string
+	json.Unmarshal(record["description"], &description)
+	return description
+}
+
+func (record syncRecord) time(field string) time.Time {
+	var t time.Time
+	json.Unmarshal(record[field], &t)
+	return t
+}
+
+// sameValue compares two JSON values regardless of their layout.
+func sameValue(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+func sameRecord(a, b syncRecord) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for field, value := range a {
+		if !sameValue(value, b[field]) {
+			return false
+		}
+	}
+	return true
+}
+
+// syncConflict is a change made on both sides that could not be merged.
+type syncConflict struct {
+	uid         string
+	description string
+	// field is empty when one side deleted the task the other changed.
+	field string
+	// kept says which version won: "local" or "remote".
+	kept string
+}
+
+// mergeRecords merges the local and remote task lists against base, the
+// list as of the last sync, task by task. A task changed on one side only
+// takes that change, deletions included. When both sides changed a task
+// the merge goes field by field: notes and status log entries from both
+// sides are kept, and for any other field changed on both sides the newer
+// version of the task wins and a conflict is reported. A task deleted on
+// one side and changed on the other is kept.
+func mergeRecords(base, local, remote map[string]syncRecord) (map[string]syncRecord, []syncConflict) {
+	merged := map[string]syncRecord{}
+	var conflicts []syncConflict
+	uids := map[string]bool{}
+	for _, records := range []map[string]syncRecord{base, local, remote} {
+		for uid := range records {
+			uids[uid] = true
+		}
+	}
+
+	for uid := range uids {
+		b, l, r := base[uid], local[uid], remote[uid]
+		switch {
+		case sameRecord(l, r):
+			merged[uid] = l
+		case sameRecord(l, b):
+			merged[uid] = r
+		case sameRecord(r, b):
+			merged[uid] = l
+		case l == nil:
+			merged[uid] = r
+			conflicts = append(conflicts, syncConflict{uid: uid, description: r.description(), kept: "remote"})
+		case r == nil:
+			merged[uid] = l
+			conflicts = append(conflicts, syncConflict{uid: uid, description: l.description(), kept: "local"})
+		default:
+			record, fields := mergeFields(b, l, r)
+			merged[uid] = record
+			for _, field := range fields {
+				kept := "local"
+				if r.time("updatedAt").After(l.time("updatedAt")) {
+					kept = "remote"
+				}
+				conflicts = append(conflicts, syncConflict{uid: uid, description: record.description(), field: field, kept: kept})
+			}
+		}
+		if merged[uid] == nil {
+			delete(merged, uid)
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].uid != conflicts[j].uid {
+			return conflicts[i].uid < conflicts[j].uid
+		}
+		return conflicts[i].field < conflicts[j].field
+	})
+	return merged, conflicts
+}
+
+// mergeFields merges one task changed on both sides and returns the fields
+// in conflict.
+func mergeFields(b, l, r syncRecord) (syncRecord, []string) {
+	newer := l
+	if r.time("updatedAt").After(l.time("updatedAt")) {
+		newer = r
+	}
+	fields := map[string]bool{}
+	for _, record := range []syncRecord{b, l, r} {
+		for field := range record {
+			fields[field] = true
+		}
+	}
+
+	merged := syncRecord{}
+	var conflicts []string
+	for field := range fields {
+		bv, lv, rv := b[field], l[field], r[field]
+		var value json.RawMessage
+		switch {
+		case sameValue(lv, rv), sameValue(rv, bv):
+			value = lv
+		case sameValue(lv, bv):
+			value = rv
+		case field == "updatedAt":
+			value = newer[field]
+		case field == "notes" || field == "statusLog":
+			value = unionEntries(lv, rv)
+		default:
+			value = newer[field]
+			conflicts = append(conflicts, field)
+		}
+		if value != nil {
+			merged[field] = value
+		}
+	}
+	sort.Strings(conflicts)
+	return merged, conflicts
+}
+
+// unionEntries joins two lists of log entries, keeping each entry once.
+func unionEntries(a, b json.RawMessage) json.RawMessage {
+	var left, right []json.RawMessage
+	json.Unmarshal(a, &left)
+	json.Unmarshal(b, &right)
+	for _, entry := range right {
+		if !slices.ContainsFunc(left, func(existing json.RawMessage) bool { return sameValue(existing, entry) }) {
+			left = append(left, entry)
+		}
+	}
+	data, _ := json.Marshal(left)
+	return data
+}
+
+// legacyUID identifies a task created before tasks had UIDs. It is derived
+// from the task itself, so copies of a task file that were passed around
+// by hand get the same UIDs and are merged rather than duplicated on their
+// first sync.
+func legacyUID(task tasks.Task) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(task.CreatedAt.UnixNano(), 10) + "\x00" + task.Description))
+	return hex.EncodeToString(sum[:16])
+}
+
+// gitRepo runs git commands in the sync repository.
+type gitRepo struct {
+	dir string
+	env []string
+}
+
+func (g gitRepo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.dir}, args...)...)
+	cmd.Env = append(os.Environ(), g.env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ok runs a git command that answers a question with its exit status.
+func (g gitRepo) ok(args ...string) bool {
+	_, err := g.run(args...)
+	return err == nil
+}
+
+// openGitRepo creates the repository when needed. Commits are made as
+// task-cli unless git already knows who the user is.
+func openGitRepo(dir string) (gitRepo, error) {
+	repo := gitRepo{dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return repo, err
+		}
+		if _, err := repo.run("init", "-q", "-b", syncBranch); err != nil {
+			return repo, err
+		}
+	}
+	if name, _ := repo.run("config", "user.name"); name == "" {
+		repo.env = append(repo.env, "GIT_AUTHOR_NAME=task-cli", "GIT_COMMITTER_NAME=task-cli")
+	}
+	if email, _ := repo.run("config", "user.email"); email == "" {
+		repo.env = append(repo.env, "GIT_AUTHOR_EMAIL=task-cli@localhost", "GIT_COMMITTER_EMAIL=task-cli@localhost")
+	}
+	return repo, nil
+}
+
+// readRecords reads the task file of a commit, or nothing when the commit
+// does not exist.
+func (g gitRepo) readRecords(rev string) (map[string]syncRecord, error) {
+	records := map[string]syncRecord{}
+	if !g.ok("rev-parse", "-q", "--verify", rev+"^{commit}") {
+		return records, nil
+	}
+	data, err := g.run("show", rev+":"+syncFileName)
+	if err != nil {
+		return nil, err
+	}
+	var list []syncRecord
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		return nil, fmt.Errorf("%s in %s is corrupt: %w", syncFileName, rev, err)
+	}
+	for _, record := range list {
+		records[record.uid()] = record
+	}
+	return records, nil
+}
+
+func writeRecords(path string, records map[string]syncRecord) error {
+	uids := make([]string, 0, len(records))
+	for uid := range records {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	list := make([]syncRecord, len(uids))
+	for i, uid := range uids {
+		list[i] = records[uid]
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tasks.WriteFileAtomic(path, append(data, '\n'))
+}
+
+// fetchRemote fetches the remote branch and reports whether it exists.
+// A remote given as a path that does not exist yet is created as a bare
+// repository.
+func (g gitRepo) fetchRemote(remote string) (bool, error) {
+	if filepath.IsAbs(remote) {
+		if _, err := os.Stat(remote); errors.Is(err, os.ErrNotExist) {
+			if err := os.MkdirAll(remote, 0755); err != nil {
+				return false, err
+			}
+			if _, err := (gitRepo{dir: remote}).run("init", "-q", "--bare", "-b", syncBranch); err != nil {
+				return false, err
+			}
+			return false, nil
+		}
+	}
+	heads, err := g.run("ls-remote", "--heads", remote, syncBranch)
+	if err != nil || heads == "" {
+		return false, err
+	}
+	_, err = g.run("fetch", "-q", remote, syncBranch)
+	return err == nil, err
+}
+
+// syncTasks merges the task list with the sync repository and its remote.
+// The merge happens here, task by task, rather than in git: the store is
+// the local side, the fetched remote branch the other side and the last
+// commit they share the base. The result is written to the store before it
+// is committed on top of both histories and pushed, so a failed commit or
+// push never makes local changes look like deletions on the next run.
+func syncTasks(store tasks.Store, config syncConfig) error {
+	repo, err := openGitRepo(config.Repo)
+	if err != nil {
+		return storageError(fmt.Errorf("opening the sync repository: %w", err))
+	}
+	// A sync that failed halfway may have left a merge open.
+	if repo.ok("rev-parse", "-q", "--verify", "MERGE_HEAD") {
+		if _, err := repo.run("merge", "--abort"); err != nil {
+			return err
+		}
+	}
+	fetched := false
+	if config.Remote != "" {
+		if fetched, err = repo.fetchRemote(config.Remote); err != nil {
+			return fmt.Errorf("fetching %s: %w", config.Remote, err)
+		}
+	}
+
+	// The base is the last version both sides share: the last sync when
+	// there is nothing to fetch, and otherwise the merge base of the two
+	// histories, or nothing at all if they started separately.
+	baseRev, remoteRev := "HEAD", "HEAD"
+	if fetched {
+		baseRev, _ = repo.run("merge-base", "HEAD", "FETCH_HEAD")
+		remoteRev = "FETCH_HEAD"
+	}
+	base := map[string]syncRecord{}
+	if baseRev != "" {
+		if base, err = repo.readRecords(baseRev); err != nil {
+			return storageError(err)
+		}
+	}
+	remote, err := repo.readRecords(remoteRev)
+	if err != nil {
+		return storageError(err)
+	}
+
+	// The histories are joined before the store changes, so a failing
+	// git command leaves everything as it was.
+	if fetched {
+		switch {
+		case !repo.ok("rev-parse", "-q", "--verify", "HEAD"), repo.ok("merge-base", "--is-ancestor", "HEAD", "FETCH_HEAD"):
+			// Nothing here the remote lacks: continue from its history.
+			_, err = repo.run("reset", "-q", "--soft", "FETCH_HEAD")
+		case repo.ok("merge-base", "--is-ancestor", "FETCH_HEAD", "HEAD"):
+		default:
+			// Record both histories; the file itself is merged below.
+			_, err = repo.run("merge", "-q", "--no-ff", "--no-commit", "--allow-unrelated-histories", "-s", "ours", "FETCH_HEAD")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// The tasks are read, merged and replaced in one transaction, so a
+	// change made here during the sync is neither lost nor undone.
+	var (
+		merged    map[string]syncRecord
+		conflicts []syncConflict
+		applied   appliedRecords
+	)
+	err = store.Modify(func(list *[]tasks.Task) error {
+		assignUIDs(*list)
+		uidOf := map[int]string{}
+		for _, task := range *list {
+			uidOf[task.ID] = task.UID
+		}
+		local := map[string]syncRecord{}
+		for _, task := range *list {
+			record, err := toRecord(task, uidOf)
+			if err != nil {
+				return err
+			}
+			local[task.UID] = record
+		}
+		merged, conflicts = mergeRecords(base, local, remote)
+		var err error
+		applied, err = applyRecords(list, merged)
+		return err
+	})
+	if err != nil {
+		return storageError(err)
+	}
+	for _, conflict := range conflicts {
+		id := applied.idOf[conflict.uid]
+		if conflict.field == "" {
+			warn("Conflict on task %d (%s): deleted on one side and changed on the other; kept the %s version", id, conflict.description, conflict.kept)
+		} else {
+			warn("Conflict on task %d (%s): %s changed on both sides; kept the newer, %s version", id, conflict.description, conflict.field, conflict.kept)
+		}
+	}
+
+	if err := writeRecords(filepath.Join(config.Repo, syncFileName), merged); err != nil {
+		return storageError(err)
+	}
+	if _, err := repo.run("add", syncFileName); err != nil {
+		return err
+	}
+	merging := repo.ok("rev-parse", "-q", "--verify", "MERGE_HEAD")
+	if merging || !repo.ok("diff", "--cached", "--quiet") {
+		host, _ := os.Hostname()
+		if _, err := repo.run("commit", "-q", "-m", "Sync tasks from "+host); err != nil {
+			return err
+		}
+	}
+	if config.Remote != "" {
+		if _, err := repo.run("push", "-q", config.Remote, "HEAD:"+syncBranch); err != nil {
+			return fmt.Errorf("pushing to %s (run sync again to merge the newer remote changes): %w", config.Remote, err)
+		}
+	}
+
+	out.info("Synced with %s: %d added, %d updated, %d deleted here, %d conflict(s)", syncTarget(config), len(applied.added), len(applied.updated), len(applied.deleted), len(conflicts))
+	out.changed(append(applied.added, applied.updated...))
+	return nil
+}
+
+func syncTarget(config syncConfig) string {
+	if config.Remote != "" {
+		return config.Remote
+	}
+	return config.Repo
+}
+
+// assignUIDs gives every task without a UID its legacy one.
+func assignUIDs(list []tasks.Task) {
+	for i := range list {
+		if list[i].UID == "" {
+			list[i].UID = legacyUID(list[i])
+		}
+	}
+}
+
+// appliedRecords is what applyRecords changed in the store.
+type appliedRecords struct {
+	added, updated, deleted []tasks.Task
+	// idOf maps every merged UID to its local ID.
+	idOf map[string]int
+}
+
+// applyRecords makes list hold exactly the merged tasks. Tasks new to this
+// machine get the next free IDs.
+func applyRecords(list *[]tasks.Task, merged map[string]syncRecord) (appliedRecords, error) {
+	result := appliedRecords{idOf: map[string]int{}}
+	byUID := map[string]tasks.Task{}
+	nextID := 0
+	for _, task := range *list {
+		byUID[task.UID] = task
+		nextID = max(nextID, task.ID)
+	}
+
+	uids := make([]string, 0, len(merged))
+	for uid := range merged {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool {
+		return merged[uids[i]].time("createdAt").Before(merged[uids[j]].time("createdAt"))
+	})
+	for _, uid := range uids {
+		if task, ok := byUID[uid]; ok {
+			result.idOf[uid] = task.ID
+		} else {
+			nextID++
+			result.idOf[uid] = nextID
+		}
+	}
+
+	for _, uid := range uids {
+		task, err := merged[uid].toTask(result.idOf[uid], result.idOf)
+		if err != nil {
+			return result, fmt.Errorf("reading synced task %s: %w", uid, err)
+		}
+		existing, ok := byUID[uid]
+		switch {
+		case !ok:
+			result.added = append(result.added, task)
+		case !sameTask(existing, task):
+			result.updated = append(result.updated, task)
+		}
+	}
+	*list = slices.DeleteFunc(*list, func(task tasks.Task) bool {
+		if _, ok := merged[task.UID]; ok {
+			return false
+		}
+		result.deleted = append(result.deleted, task)
+		return true
+	})
+	for _, task := range result.updated {
+		(*list)[indexOf(*list, task.ID)] = task
+	}
+	*list = append(*list, result.added...)
+	return result, nil
+}
+
+func sameTask(a, b tasks.Task) bool {
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(da, db)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"task-cli/tasks"
+)
+
+func TestMergeRecords(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2026, 1, n, 0, 0, 0, 0, time.UTC) }
+	records := func(list ...tasks.Task) map[string]syncRecord {
+		result := map[string]syncRecord{}
+		for _, task := range list {
+			record, err := toRecord(task, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result[task.UID] = record
+		}
+		return result
+	}
+	task := func(uid, description string, updated int) tasks.Task {
+		return tasks.Task{UID: uid, Description: description, Status: tasks.StatusTaskTodo, UpdatedAt: day(updated)}
+	}
+	withPriority := func(task tasks.Task, p tasks.Priority) tasks.Task { task.Priority = p; return task }
+	withNote := func(task tasks.Task, text string) tasks.Task {
+		task.Notes = append(task.Notes, tasks.Note{Text: text, CreatedAt: task.UpdatedAt})
+		return task
+	}
+
+	base := records(task("a", "edited here", 1), task("b", "edited there", 1), task("c", "deleted here", 1),
+		task("d", "both fields", 1), task("e", "same field", 1), task("f", "deleted and changed", 1))
+	local := records(task("a", "edited here!", 2), task("b", "edited there", 1),
+		withPriority(task("d", "both fields", 2), tasks.PriorityHigh), task("e", "local wording", 2),
+		withNote(task("d2", "new here", 2), "x"))
+	remote := records(task("a", "edited here", 1), task("b", "edited there!", 3), task("c", "deleted here", 1),
+		withNote(task("d", "both fields, renamed", 3), "from remote"), task("e", "remote wording", 3),
+		task("f", "changed there", 3))
+
+	merged, conflicts := mergeRecords(base, local, remote)
+
+	want := map[string]string{
+		"a": "edited here!", "b": "edited there!", "d": "both fields, renamed",
+		"e": "remote wording", "f": "changed there", "d2": "new here",
+	}
+	if len(merged) != len(want) {
+		t.Errorf("merged %d task(s), want %d", len(merged), len(want))
+	}
+	for uid, description := range want {
+		got, err := merged[uid].toTask(1, nil)
+		if err != nil || got.Description != description {
+			t.Errorf("task %s = %q (%v), want %q", uid, got.Description, err, description)
+		}
+	}
+	d, _ := merged["d"].toTask(1, nil)
+	if d.Priority != tasks.PriorityHigh || len(d.Notes) != 1 {
+		t.Errorf("task d = %+v, want the local priority and the remote note", d)
+	}
+
+	var got []string
+	for _, c := range conflicts {
+		got = append(got, c.uid+":"+c.field+":"+c.kept)
+	}
+	if strings.Join(got, " ") != "e:description:remote f::remote" {
+		t.Errorf("conflicts = %v", got)
+	}
+}
+
+func TestSyncBetweenCopies(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	laptop, server := filepath.Join(dir, "laptop"), filepath.Join(dir, "server")
+	for _, copy := range []string{laptop, server} {
+		runCLI(t, dir, "--file", copy+".json", "add", "shared")
+	}
+	// Both copies have a task 1 of their own; after syncing both keep
+	// theirs and gain the other one under a new ID.
+	runCLI(t, dir, "--file", laptop+".json", "sync", "--repo", filepath.Join(dir, "laptop-repo"), "--remote", remote)
+	runCLI(t, dir, "--file", server+".json", "sync", "--repo", filepath.Join(dir, "server-repo"), "--remote", remote)
+	runCLI(t, dir, "--file", laptop+".json", "sync")
+
+	for _, copy := range []string{laptop, server} {
+		stdout := runCLI(t, dir, "--file", copy+".json", "list", "--output", "csv")
+		if strings.Count(stdout, "shared") != 2 {
+			t.Errorf("%s after sync:\n%s", filepath.Base(copy), stdout)
+		}
+	}
+
+	runCLI(t, dir, "--file", server+".json", "mark-done", "1")
+	runCLI(t, dir, "--file", server+".json", "sync")
+	runCLI(t, dir, "--file", laptop+".json", "sync")
+	if stdout := runCLI(t, dir, "--file", laptop+".json", "list", "done"); !strings.Contains(stdout, "shared") {
+		t.Errorf("the server's change did not reach the laptop:\n%s", stdout)
+	}
+}
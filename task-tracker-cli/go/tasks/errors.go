@@ -0,0 +1,145 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned by stores for a task ID they do not hold. The
+// operations return a *NotFoundError naming the ID, which matches it with
+// errors.Is.
+var ErrNotFound = errors.New("task not found")
+
+type NotFoundError struct {
+	ID int
+	// Archived is set when the task was looked for in the archive.
+	Archived bool
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Archived {
+		return fmt.Sprintf("archived task with ID %d not found", e.ID)
+	}
+	return fmt.Sprintf("task with ID %d not found", e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// InvalidStatusError reports a status the workflow does not define.
+type InvalidStatusError struct {
+	Status StatusTask
+	Valid  []StatusTask
+}
+
+func (e *InvalidStatusError) Error() string {
+	return fmt.Sprintf("unknown status %q (use %s)", e.Status, JoinStatuses(e.Valid))
+}
+
+// TransitionError reports a move the workflow does not allow.
+type TransitionError struct {
+	ID       int
+	From, To StatusTask
+	// Allowed lists where the task may move instead.
+	Allowed []StatusTask
+}
+
+func (e *TransitionError) Error() string {
+	allowed := "none"
+	if len(e.Allowed) > 0 {
+		allowed = JoinStatuses(e.Allowed)
+	}
+	return fmt.Sprintf("task %d cannot move from %s to %s (allowed: %s)", e.ID, e.From, e.To, allowed)
+}
+
+// BlockedError refuses to finish a task while tasks blocking it are open.
+type BlockedError struct {
+	ID       int
+	Status   StatusTask
+	Blockers []int
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("task %d is blocked by open task(s) %s", e.ID, JoinIDs(e.Blockers))
+}
+
+// SubtasksError refuses to delete a task that has subtasks without
+// deleting them too.
+type SubtasksError struct {
+	ID int
+}
+
+func (e *SubtasksError) Error() string {
+	return fmt.Sprintf("task %d has subtasks", e.ID)
+}
+
+// ErrNoTimer is returned when the timer is to be stopped but none is
+// running.
+var ErrNoTimer = errors.New("no timer is running")
+
+// TimerRunningError refuses to start the timer of task ID while the timer
+// of task Running, possibly the same one, is still going.
+type TimerRunningError struct {
+	ID      int
+	Running int
+}
+
+func (e *TimerRunningError) Error() string {
+	if e.ID == e.Running {
+		return fmt.Sprintf("task %d is already being timed", e.ID)
+	}
+	return fmt.Sprintf("task %d is already being timed; stop it first", e.Running)
+}
+
+// NotTimedError refuses to stop the timer of task ID because the running
+// timer is that of task Running.
+type NotTimedError struct {
+	ID      int
+	Running int
+}
+
+func (e *NotTimedError) Error() string {
+	return fmt.Sprintf("task %d is not being timed (task %d is)", e.ID, e.Running)
+}
+
+// LinkError reports a parent or blocker that does not exist or would
+// create a cycle.
+type LinkError struct {
+	ID     int
+	Reason string
+}
+
+func (e *LinkError) Error() string { return e.Reason }
+
+func linkError(id int, format string, args ...any) error {
+	return &LinkError{ID: id, Reason: fmt.Sprintf(format, args...)}
+}
+
+// StoreError wraps a failure to read or write the store, as opposed to a
+// change the operation refused.
+type StoreError struct {
+	Err error
+}
+
+func (e *StoreError) Error() string { return e.Err.Error() }
+func (e *StoreError) Unwrap() error { return e.Err }
+
+// storeError marks err as a store failure unless it already says which
+// task was missing.
+func storeError(err error) error {
+	var notFound *NotFoundError
+	if err == nil || errors.As(err, &notFound) {
+		return err
+	}
+	return &StoreError{Err: err}
+}
+
+// JoinIDs formats ids as a comma separated list.
+func JoinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
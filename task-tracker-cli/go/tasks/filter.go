@@ -0,0 +1,108 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter selects tasks with space separated terms such as
+// "status:todo tag:backend project:api -tag:blocked".
+//
+// Terms on different fields must all match, while several terms on the same
+// field match when any of them does. A leading "-" excludes the tasks the
+// term matches. A bare word is read as a status when it names one and as a
+// description search otherwise, so "list done" keeps working.
+type Filter struct {
+	include map[string][]string
+	exclude map[string][]string
+}
+
+// FilterFields are the fields filter terms may name.
+var FilterFields = []string{"status", "tag", "project", "priority", "text"}
+
+func ParseFilter(args []string, w Workflow) (Filter, error) {
+	f := Filter{include: map[string][]string{}, exclude: map[string][]string{}}
+	for _, arg := range args {
+		for _, term := range strings.Fields(arg) {
+			target := f.include
+			if strings.HasPrefix(term, "-") {
+				target = f.exclude
+				term = term[1:]
+			}
+
+			field, value, ok := strings.Cut(term, ":")
+			if !ok {
+				field, value = "text", term
+				if w.Known(StatusTask(term)) {
+					field = "status"
+				}
+			}
+			if !IsFilterField(field) {
+				return Filter{}, fmt.Errorf("unknown filter field %q (use %s)", field, strings.Join(FilterFields, ", "))
+			}
+			if value == "" {
+				return Filter{}, fmt.Errorf("empty value in filter term %q", term)
+			}
+			target[field] = append(target[field], value)
+		}
+	}
+	return f, nil
+}
+
+func IsFilterField(field string) bool {
+	for _, f := range FilterFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) IsEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+func (f Filter) Match(task Task) bool {
+	for field, values := range f.include {
+		if !matchAny(task, field, values) {
+			return false
+		}
+	}
+	for field, values := range f.exclude {
+		if matchAny(task, field, values) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(task Task, field string, values []string) bool {
+	for _, value := range values {
+		if matchTerm(task, field, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTerm(task Task, field, value string) bool {
+	switch field {
+	case "status":
+		return string(task.Status) == value
+	case "tag":
+		return task.HasTag(value)
+	case "project":
+		return strings.EqualFold(task.Project, value)
+	case "priority":
+		return string(task.Priority) == value
+	case "text":
+		return strings.Contains(strings.ToLower(task.Description), strings.ToLower(value))
+	}
+	return false
+}
+
+// includes returns the values of the positive terms given for field, which
+// lets a store narrow its query before Match runs on the candidates.
+func (f Filter) includes(field string) []string {
+	return f.include[field]
+}
@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Description: "Fix login bug", Status: StatusTaskTodo, Priority: PriorityHigh, Project: "api", Tags: []string{"backend"}},
+		{ID: 2, Description: "Write docs", Status: StatusTaskDone, Project: "API", Tags: []string{"docs"}},
+		{ID: 3, Description: "Review login page", Status: StatusTaskInProgress, Priority: PriorityLow, Tags: []string{"Backend", "blocked"}},
+		{ID: 4, Description: "Plan sprint", Status: "review"},
+	}
+	w := DefaultWorkflow
+	w.Statuses = append(w.Statuses[:len(w.Statuses):len(w.Statuses)], "review")
+
+	tests := []struct {
+		args []string
+		want []int
+	}{
+		{nil, []int{1, 2, 3, 4}},
+		{[]string{"status:todo"}, []int{1}},
+		{[]string{"done"}, []int{2}},
+		{[]string{"review"}, []int{4}},
+		{[]string{"login"}, []int{1, 3}},
+		{[]string{"tag:backend"}, []int{1, 3}},
+		{[]string{"tag:backend -tag:blocked"}, []int{1}},
+		{[]string{"project:api"}, []int{1, 2}},
+		{[]string{"status:todo status:done"}, []int{1, 2}},
+		{[]string{"project:api", "-done"}, []int{1}},
+		{[]string{"priority:low text:LOGIN"}, []int{3}},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.args, w)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.args, err)
+			continue
+		}
+		var got []int
+		for _, task := range tasks {
+			if filter.Match(task) {
+				got = append(got, task.ID)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filter %q matched %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	// Without the custom status, "review" is a description search.
+	filter, _ := ParseFilter([]string{"review"}, DefaultWorkflow)
+	if !filter.Match(tasks[2]) || filter.Match(tasks[3]) {
+		t.Error("review under the default workflow should search descriptions")
+	}
+
+	for _, args := range [][]string{{"owner:me"}, {"tag:"}, {"-status:"}} {
+		if _, err := ParseFilter(args, w); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package tasks
+
+// Index looks tasks up by ID when following parent and blocked-by links.
+type Index map[int]Task
+
+func NewIndex(tasks []Task) Index {
+	index := make(Index, len(tasks))
+	for _, task := range tasks {
+		index[task.ID] = task
+	}
+	return index
+}
+
+// ValidateLinks checks that the parent and blockers of task exist and that
+// neither the parent chain nor the blocked-by graph loops back to it. The
+// index must hold every stored task.
+func (index Index) ValidateLinks(task Task) error {
+	if task.ParentID != 0 {
+		if task.ParentID == task.ID {
+			return linkError(task.ID, "task %d cannot be its own parent", task.ID)
+		}
+		if _, ok := index[task.ParentID]; !ok {
+			return linkError(task.ID, "parent task %d not found", task.ParentID)
+		}
+		seen := map[int]bool{}
+		for id := task.ParentID; id != 0; id = index[id].ParentID {
+			if id == task.ID {
+				return linkError(task.ID, "making %d the parent of task %d would create a cycle", task.ParentID, task.ID)
+			}
+			if seen[id] {
+				break
+			}
+			seen[id] = true
+		}
+	}
+
+	for _, blocker := range task.BlockedBy {
+		if blocker == task.ID {
+			return linkError(task.ID, "task %d cannot block itself", task.ID)
+		}
+		if _, ok := index[blocker]; !ok {
+			return linkError(task.ID, "blocking task %d not found", blocker)
+		}
+		if task.ID != 0 && index.dependsOn(blocker, task.ID, map[int]bool{}) {
+			return linkError(task.ID, "task %d already depends on task %d, blocking would create a cycle", blocker, task.ID)
+		}
+	}
+	return nil
+}
+
+// dependsOn reports whether id is blocked, directly or transitively, by target.
+func (index Index) dependsOn(id, target int, visited map[int]bool) bool {
+	if visited[id] {
+		return false
+	}
+	visited[id] = true
+	for _, blocker := range index[id].BlockedBy {
+		if blocker == target || index.dependsOn(blocker, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenBlockers returns the blockers of task that are not finished yet in
+// workflow w.
+func (index Index) OpenBlockers(task Task, w Workflow) []int {
+	var open []int
+	for _, blocker := range task.BlockedBy {
+		if b, ok := index[blocker]; ok && !w.IsTerminal(b.Status) {
+			open = append(open, blocker)
+		}
+	}
+	return open
+}
+
+func (index Index) Children(id int) []Task {
+	var children []Task
+	for _, task := range index {
+		if task.ParentID == id {
+			children = append(children, task)
+		}
+	}
+	return children
+}
+
+// Descendants returns the IDs of every subtask below id, depth first.
+func (index Index) Descendants(id int) []int {
+	var ids []int
+	for _, child := range index.Children(id) {
+		ids = append(ids, child.ID)
+		ids = append(ids, index.Descendants(child.ID)...)
+	}
+	return ids
+}
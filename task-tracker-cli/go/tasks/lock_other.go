@@ -0,0 +1,33 @@
+//go:build !unix
+
+package tasks
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+var errLocked = errors.New("lock held by another process")
+
+// staleLockAge is how old a lock file must be before it is treated as left
+// behind by a crashed process.
+const staleLockAge = time.Minute
+
+// tryLock creates path exclusively; its existence is the lock.
+func tryLock(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+		}
+		return nil, errLocked
+	}
+	f.Close()
+	return func() {
+		os.Remove(path)
+	}, nil
+}
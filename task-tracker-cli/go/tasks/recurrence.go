@@ -0,0 +1,176 @@
+package tasks
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceKind string
+
+const (
+	RecurDaily    RecurrenceKind = "daily"
+	RecurWeekly   RecurrenceKind = "weekly"
+	RecurMonthly  RecurrenceKind = "monthly"
+	RecurInterval RecurrenceKind = "every"
+)
+
+// Recurrence describes when the next occurrence of a repeating task is due.
+type Recurrence struct {
+	Kind RecurrenceKind `json:"kind"`
+	// Days is the gap for "every" rules.
+	Days int `json:"days,omitempty"`
+	// Weekdays lists the days a weekly rule repeats on, as mon..sun.
+	Weekdays []string `json:"weekdays,omitempty"`
+	// MonthDay is the day of the month for monthly rules. Months that are
+	// too short use their last day.
+	MonthDay int `json:"monthDay,omitempty"`
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseRecurrence reads a rule such as "daily", "weekly:mon,thu",
+// "monthly:15" or "every:3d". Weekly and monthly rules without a value
+// repeat on the weekday or day of month of the due date.
+func ParseRecurrence(value string, due *time.Time) (*Recurrence, error) {
+	anchor := time.Now()
+	if due != nil {
+		anchor = *due
+	}
+
+	kind, arg, _ := strings.Cut(strings.ToLower(value), ":")
+	switch RecurrenceKind(kind) {
+	case RecurDaily:
+		if arg != "" {
+			break
+		}
+		return &Recurrence{Kind: RecurDaily}, nil
+
+	case RecurWeekly:
+		if arg == "" {
+			return &Recurrence{Kind: RecurWeekly, Weekdays: []string{weekdayNames[anchor.Weekday()]}}, nil
+		}
+		var days []string
+		for _, name := range strings.Split(arg, ",") {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(days, day) {
+				days = append(days, day)
+			}
+		}
+		return &Recurrence{Kind: RecurWeekly, Weekdays: days}, nil
+
+	case RecurMonthly:
+		if arg == "" {
+			return &Recurrence{Kind: RecurMonthly, MonthDay: anchor.Day()}, nil
+		}
+		day, err := strconv.Atoi(arg)
+		if err != nil || day < 1 || day > 31 {
+			return nil, fmt.Errorf("invalid day of month %q (use 1-31)", arg)
+		}
+		return &Recurrence{Kind: RecurMonthly, MonthDay: day}, nil
+
+	case RecurInterval:
+		days, err := strconv.Atoi(strings.TrimSuffix(arg, "d"))
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("invalid interval %q (use every:<days>d)", arg)
+		}
+		return &Recurrence{Kind: RecurInterval, Days: days}, nil
+	}
+	return nil, fmt.Errorf("invalid repeat rule %q (use daily, weekly[:mon,thu], monthly[:15] or every:<days>d)", value)
+}
+
+func parseWeekday(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) >= 3 {
+		if i := slices.Index(weekdayNames, name[:3]); i >= 0 {
+			return weekdayNames[i], nil
+		}
+	}
+	return "", fmt.Errorf("invalid weekday %q", name)
+}
+
+func (r Recurrence) String() string {
+	switch r.Kind {
+	case RecurWeekly:
+		return "weekly:" + strings.Join(r.Weekdays, ",")
+	case RecurMonthly:
+		return "monthly:" + strconv.Itoa(r.MonthDay)
+	case RecurInterval:
+		return "every:" + strconv.Itoa(r.Days) + "d"
+	}
+	return string(r.Kind)
+}
+
+// next returns the first occurrence strictly after from.
+func (r Recurrence) next(from time.Time) time.Time {
+	from = StartOfDay(from)
+	switch r.Kind {
+	case RecurWeekly:
+		for i := 1; i <= 7; i++ {
+			day := from.AddDate(0, 0, i)
+			if slices.Contains(r.Weekdays, weekdayNames[day.Weekday()]) {
+				return day
+			}
+		}
+	case RecurMonthly:
+		candidate := monthDay(from.Year(), from.Month(), r.MonthDay, from.Location())
+		if !candidate.After(from) {
+			candidate = monthDay(from.Year(), from.Month()+1, r.MonthDay, from.Location())
+		}
+		return candidate
+	case RecurInterval:
+		return from.AddDate(0, 0, r.Days)
+	}
+	return from.AddDate(0, 0, 1)
+}
+
+// monthDay returns day of the given month, clamped to the month's length.
+func monthDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// AddMonths moves day n months on, to the same day of the month or to the
+// last day of a shorter month, so January 31 moves to the end of February
+// rather than into March.
+func AddMonths(day time.Time, n int) time.Time {
+	return monthDay(day.Year(), day.Month()+time.Month(n), day.Day(), day.Location())
+}
+
+// nextOccurrence builds the follow-up of a recurring task that has just been
+// completed. The new due date is the first occurrence after both the old due
+// date and today, so finishing an overdue task does not produce another
+// overdue one.
+func nextOccurrence(task Task, initial StatusTask, now time.Time) (Task, bool) {
+	if task.Recurrence == nil || task.NextID != 0 {
+		return Task{}, false
+	}
+
+	from := now
+	if task.DueDate != nil {
+		from = *task.DueDate
+	}
+	due := task.Recurrence.next(from)
+	for !due.After(StartOfDay(now)) {
+		due = task.Recurrence.next(due)
+	}
+
+	return Task{
+		Description: task.Description,
+		Status:      initial,
+		Priority:    task.Priority,
+		DueDate:     &due,
+		Project:     task.Project,
+		Tags:        slices.Clone(task.Tags),
+		ParentID:    task.ParentID,
+		Recurrence:  task.Recurrence,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, true
+}
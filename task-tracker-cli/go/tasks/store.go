@@ -0,0 +1,51 @@
+package tasks
+
+import "fmt"
+
+// Store is the persistence layer behind every operation. The mutating
+// methods accept several tasks so bulk changes run as a single write.
+// Missing tasks are reported with ErrNotFound.
+type Store interface {
+	Get(id int) (Task, error)
+	List(filter Filter) ([]Task, error)
+	// Create stores new tasks and returns them as saved. A zero ID is
+	// replaced by the next free one; a non-zero ID is kept and must not
+	// exist yet. An empty UID is filled in with a new one.
+	Create(tasks ...Task) ([]Task, error)
+	Update(tasks ...Task) error
+	Delete(ids ...int) error
+	// Modify runs fn on every stored task as one transaction: fn may
+	// change, add or drop tasks in the list, and the result is saved only
+	// when fn returns nil. Concurrent writers, in this process or
+	// another, wait for each other, so a change computed from what fn
+	// read cannot overwrite one made meanwhile. Added tasks need an unused
+	// ID, such as the one NextID returns.
+	Modify(fn func(tasks *[]Task) error) error
+	Close() error
+}
+
+// NextID is the ID a new task added to tasks gets. IDs of deleted tasks
+// are not handed out again while a higher one is still in use.
+func NextID(tasks []Task) int {
+	maxID := 0
+	for _, task := range tasks {
+		maxID = max(maxID, task.ID)
+	}
+	return maxID + 1
+}
+
+// checkIDs refuses a task list that Modify cannot save: one with a task
+// lacking an ID or two tasks sharing one.
+func checkIDs(tasks []Task) error {
+	seen := make(map[int]bool, len(tasks))
+	for _, task := range tasks {
+		if task.ID <= 0 {
+			return fmt.Errorf("task %q has no ID", task.Description)
+		}
+		if seen[task.ID] {
+			return fmt.Errorf("task with ID %d already exists", task.ID)
+		}
+		seen[task.ID] = true
+	}
+	return nil
+}
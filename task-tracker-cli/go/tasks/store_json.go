@@ -0,0 +1,303 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// BackupCount is how many previous versions of the task file are kept
+	// as tasks.json.bak.1 (newest) to tasks.json.bak.N (oldest).
+	BackupCount = 3
+
+	lockTimeout    = 10 * time.Second
+	lockRetryDelay = 20 * time.Millisecond
+)
+
+// JSONStore keeps every task in a single JSON file. Reads go straight to the
+// file, which is only ever replaced atomically; writes run under an
+// advisory lock.
+type JSONStore struct {
+	path string
+}
+
+func NewJSONStore(path string) *JSONStore {
+	return &JSONStore{path: path}
+}
+
+// Path is the task file of the store.
+func (s *JSONStore) Path() string {
+	return s.path
+}
+
+func (s *JSONStore) lockFileName() string {
+	return s.path + ".lock"
+}
+
+func (s *JSONStore) BackupFileName(n int) string {
+	return s.path + ".bak." + strconv.Itoa(n)
+}
+
+func (s *JSONStore) Get(id int) (Task, error) {
+	tasks, err := s.load()
+	if err != nil {
+		return Task{}, err
+	}
+	for _, task := range tasks {
+		if task.ID == id {
+			return task, nil
+		}
+	}
+	return Task{}, ErrNotFound
+}
+
+func (s *JSONStore) List(filter Filter) ([]Task, error) {
+	tasks, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	matched := []Task{}
+	for _, task := range tasks {
+		if filter.Match(task) {
+			matched = append(matched, task)
+		}
+	}
+	return matched, nil
+}
+
+func (s *JSONStore) Create(newTasks ...Task) ([]Task, error) {
+	created := make([]Task, 0, len(newTasks))
+	err := s.Modify(func(tasks *[]Task) error {
+		for _, task := range newTasks {
+			if task.UID == "" {
+				task.UID = NewUID()
+			}
+			if task.ID == 0 {
+				task.ID = NextID(*tasks)
+			} else if containsTask(*tasks, task.ID) {
+				return fmt.Errorf("task with ID %d already exists", task.ID)
+			}
+			*tasks = append(*tasks, task)
+			created = append(created, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *JSONStore) Update(updated ...Task) error {
+	return s.Modify(func(tasks *[]Task) error {
+		for _, task := range updated {
+			i := indexOfTask(*tasks, task.ID)
+			if i < 0 {
+				return ErrNotFound
+			}
+			(*tasks)[i] = task
+		}
+		return nil
+	})
+}
+
+func (s *JSONStore) Delete(ids ...int) error {
+	return s.Modify(func(tasks *[]Task) error {
+		for _, id := range ids {
+			i := indexOfTask(*tasks, id)
+			if i < 0 {
+				return ErrNotFound
+			}
+			*tasks = append((*tasks)[:i], (*tasks)[i+1:]...)
+		}
+		return nil
+	})
+}
+
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+func indexOfTask(tasks []Task, id int) int {
+	for i, task := range tasks {
+		if task.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsTask(tasks []Task, id int) bool {
+	return indexOfTask(tasks, id) >= 0
+}
+
+func (s *JSONStore) load() ([]Task, error) {
+	file, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Task{}, nil
+		}
+		return nil, err
+	}
+
+	var tasks []Task
+	err = json.Unmarshal(file, &tasks)
+	if err != nil {
+		return nil, fmt.Errorf("%s is corrupt (%v); run \"task-cli recover\" to restore the latest backup", s.path, err)
+	}
+	return tasks, nil
+}
+
+// save replaces the task file atomically and rotates the previous version
+// into the backups, unless nothing changed. Callers must hold the store
+// lock.
+func (s *JSONStore) save(tasks []Task) error {
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	previous, err := os.ReadFile(s.path)
+	if err == nil {
+		if bytes.Equal(previous, data) {
+			return nil
+		}
+		if err := s.rotateBackups(previous); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return WriteFileAtomic(s.path, data)
+}
+
+// Modify runs one load-modify-save cycle while holding the store lock, so
+// concurrent task-cli processes cannot lose each other's updates. The file
+// is left untouched when fn returns an error.
+func (s *JSONStore) Modify(fn func(tasks *[]Task) error) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tasks, err := s.load()
+	if err != nil {
+		return fmt.Errorf("loading tasks: %w", err)
+	}
+
+	if err := fn(&tasks); err != nil {
+		return err
+	}
+	if err := checkIDs(tasks); err != nil {
+		return err
+	}
+
+	if err := s.save(tasks); err != nil {
+		return fmt.Errorf("saving tasks: %w", err)
+	}
+	return nil
+}
+
+// lock takes the advisory lock guarding the task file.
+func (s *JSONStore) lock() (func(), error) {
+	return AcquireLock(s.lockFileName(), s.path)
+}
+
+// AcquireLock takes the advisory lock at lockPath, retrying until
+// lockTimeout while another process holds it. name is the file the lock
+// protects and only appears in error messages.
+func AcquireLock(lockPath, name string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		unlock, err := tryLock(lockPath)
+		if err == nil {
+			return unlock, nil
+		}
+		if !errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("locking %s: %w", name, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another task-cli process", name)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// WriteFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers see either the old or the new content.
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+func (s *JSONStore) rotateBackups(previous []byte) error {
+	for n := BackupCount; n > 1; n-- {
+		err := os.Rename(s.BackupFileName(n-1), s.BackupFileName(n))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return WriteFileAtomic(s.BackupFileName(1), previous)
+}
+
+// Recover replaces the task file with backup n after checking that the
+// backup itself is readable, and returns the restored tasks. The current
+// file is kept as tasks.json.corrupt.
+func (s *JSONStore) Recover(n int) ([]Task, error) {
+	if n < 1 || n > BackupCount {
+		return nil, fmt.Errorf("backup number must be between 1 and %d", BackupCount)
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.BackupFileName(n))
+	if err != nil {
+		return nil, fmt.Errorf("reading backup: %w", err)
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("backup %s is not valid either: %w", s.BackupFileName(n), err)
+	}
+
+	if current, err := os.ReadFile(s.path); err == nil {
+		if err := WriteFileAtomic(s.path+".corrupt", current); err != nil {
+			return nil, fmt.Errorf("keeping current file: %w", err)
+		}
+	}
+	if err := WriteFileAtomic(s.path, data); err != nil {
+		return nil, fmt.Errorf("restoring backup: %w", err)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveTasksRotatesBackups(t *testing.T) {
+	store := NewJSONStore(filepath.Join(t.TempDir(), "tasks.json"))
+
+	for i := 1; i <= BackupCount+2; i++ {
+		tasks := []Task{{ID: i, Description: fmt.Sprintf("version %d", i), Status: StatusTaskTodo}}
+		if err := store.save(tasks); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n := 1; n <= BackupCount; n++ {
+		data, err := os.ReadFile(store.BackupFileName(n))
+		if err != nil {
+			t.Fatalf("backup %d missing: %v", n, err)
+		}
+		var tasks []Task
+		if err := json.Unmarshal(data, &tasks); err != nil {
+			t.Fatal(err)
+		}
+		if want := BackupCount + 2 - n; tasks[0].ID != want {
+			t.Errorf("backup %d holds version %d, want %d", n, tasks[0].ID, want)
+		}
+	}
+	if _, err := os.Stat(store.BackupFileName(BackupCount + 1)); !os.IsNotExist(err) {
+		t.Errorf("more than %d backups kept", BackupCount)
+	}
+}
+
+// TestJSONRoundTrip checks that every field survives the task file, under
+// the names older versions wrote.
+func TestJSONRoundTrip(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC) }
+	due, end := at(20, 0), at(11, 12)
+	task := Task{
+		UID:         "0123456789abcdef",
+		Description: "write the report",
+		Status:      StatusTaskInProgress,
+		Priority:    PriorityHigh,
+		DueDate:     &due,
+		Project:     "work",
+		Tags:        []string{"writing", "q1"},
+		ParentID:    1,
+		BlockedBy:   []int{1},
+		Recurrence:  &Recurrence{Kind: RecurWeekly, Weekdays: []string{"mon", "thu"}},
+		NextID:      5,
+		TimeLog:     []WorkInterval{{Start: at(11, 9), End: &end}, {Start: at(12, 9)}},
+		Notes:       []Note{{Text: "first draft\nsent to Sam", CreatedAt: at(11, 12)}},
+		StatusLog:   []StatusLogEntry{{From: StatusTaskTodo, To: StatusTaskInProgress, At: at(11, 9)}},
+		CreatedAt:   at(10, 8),
+		UpdatedAt:   at(12, 9),
+	}
+
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewJSONStore(path)
+	if _, err := store.Create(Task{Description: "parent", Status: StatusTaskTodo}); err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.Create(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.ID = created[0].ID
+
+	got, err := NewJSONStore(path).Get(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, task) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, task)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{
+		`"id"`, `"uid"`, `"description"`, `"status"`, `"priority"`, `"dueDate"`, `"project"`, `"tags"`,
+		`"parentId"`, `"blockedBy"`, `"recurrence"`, `"kind"`, `"weekdays"`, `"nextId"`, `"timeLog"`,
+		`"start"`, `"end"`, `"notes"`, `"text"`, `"statusLog"`, `"from"`, `"to"`, `"at"`, `"createdAt"`, `"updatedAt"`,
+	} {
+		if !strings.Contains(string(data), key+":") {
+			t.Errorf("task file has no %s field:\n%s", key, data)
+		}
+	}
+}
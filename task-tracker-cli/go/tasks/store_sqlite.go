@@ -0,0 +1,280 @@
+package tasks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps tasks in an embedded SQLite database. Status and project
+// get their own indexed columns for filtering; the full task is stored as
+// JSON so new task fields do not need a schema change.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id      INTEGER PRIMARY KEY,
+	status  TEXT NOT NULL,
+	project TEXT NOT NULL DEFAULT '',
+	data    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tasks_status ON tasks (status);
+CREATE INDEX IF NOT EXISTS tasks_project ON tasks (project COLLATE NOCASE);
+`
+
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("preparing %s: %w", path, err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Get(id int) (Task, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM tasks WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, ErrNotFound
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	return decodeTask(data)
+}
+
+func (s *SQLiteStore) List(filter Filter) ([]Task, error) {
+	query := `SELECT data FROM tasks`
+	var (
+		conditions []string
+		args       []any
+	)
+	if statuses := filter.includes("status"); len(statuses) > 0 {
+		conditions = append(conditions, "status IN ("+placeholders(len(statuses))+")")
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+	if projects := filter.includes("project"); len(projects) > 0 {
+		conditions = append(conditions, "project COLLATE NOCASE IN ("+placeholders(len(projects))+")")
+		for _, project := range projects {
+			args = append(args, project)
+		}
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []Task{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		task, err := decodeTask(data)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, rows.Err()
+}
+
+func (s *SQLiteStore) Create(newTasks ...Task) ([]Task, error) {
+	created := make([]Task, 0, len(newTasks))
+	err := s.inTx(func(tx *sql.Tx) error {
+		for _, task := range newTasks {
+			if task.UID == "" {
+				task.UID = NewUID()
+			}
+			var id any
+			if task.ID != 0 {
+				id = task.ID
+			}
+			result, err := tx.Exec(`INSERT INTO tasks (id, status, project, data) VALUES (?, ?, ?, '')`,
+				id, task.Status, task.Project)
+			if err != nil {
+				if task.ID != 0 {
+					return fmt.Errorf("task with ID %d already exists", task.ID)
+				}
+				return err
+			}
+			newID, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			task.ID = int(newID)
+			if err := updateRow(tx, task); err != nil {
+				return err
+			}
+			created = append(created, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *SQLiteStore) Update(tasks ...Task) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, task := range tasks {
+			if err := updateRow(tx, task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) Delete(ids ...int) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, id := range ids {
+			result, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+			if err != nil {
+				return err
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				return ErrNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// Modify loads every task inside an immediate transaction, which holds the
+// database write lock from the start, and writes back only the rows fn
+// added, changed or dropped.
+func (s *SQLiteStore) Modify(fn func(tasks *[]Task) error) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT id, data FROM tasks ORDER BY id`)
+		if err != nil {
+			return err
+		}
+		stored := map[int]string{}
+		tasks := []Task{}
+		for rows.Next() {
+			var (
+				id   int
+				data string
+			)
+			if err := rows.Scan(&id, &data); err != nil {
+				rows.Close()
+				return err
+			}
+			task, err := decodeTask(data)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			stored[id] = data
+			tasks = append(tasks, task)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(&tasks); err != nil {
+			return err
+		}
+		if err := checkIDs(tasks); err != nil {
+			return err
+		}
+
+		kept := make(map[int]bool, len(tasks))
+		for _, task := range tasks {
+			kept[task.ID] = true
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			old, exists := stored[task.ID]
+			switch {
+			case !exists:
+				_, err = tx.Exec(`INSERT INTO tasks (id, status, project, data) VALUES (?, ?, ?, ?)`,
+					task.ID, task.Status, task.Project, string(data))
+			case old != string(data):
+				err = updateRow(tx, task)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for id := range stored {
+			if kept[id] {
+				continue
+			}
+			if _, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// inTx runs fn in an immediate transaction so concurrent writers queue on
+// the busy timeout instead of failing halfway through.
+func (s *SQLiteStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func updateRow(tx *sql.Tx, task Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`UPDATE tasks SET status = ?, project = ?, data = ? WHERE id = ?`,
+		task.Status, task.Project, string(data), task.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func decodeTask(data string) (Task, error) {
+	var task Task
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		return Task{}, fmt.Errorf("decoding stored task: %w", err)
+	}
+	return task, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,123 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stores opens an empty store of every backend.
+func stores(t *testing.T) map[string]Store {
+	dir := t.TempDir()
+	db, err := OpenSQLiteStore(filepath.Join(dir, "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return map[string]Store{
+		"json":   NewJSONStore(filepath.Join(dir, "tasks.json")),
+		"sqlite": db,
+	}
+}
+
+func TestStoreAssignsIDs(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			created, err := store.Create(Task{Description: "first"}, Task{ID: 7, Description: "explicit"}, Task{Description: "after"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range []int{1, 7, 8} {
+				if created[i].ID != want {
+					t.Errorf("task %d got ID %d, want %d", i, created[i].ID, want)
+				}
+				if created[i].UID == "" {
+					t.Errorf("task %d got no UID", i)
+				}
+			}
+			if created[0].UID == created[1].UID {
+				t.Errorf("tasks share the UID %s", created[0].UID)
+			}
+
+			if _, err := store.Create(Task{ID: 7, Description: "duplicate"}); err == nil {
+				t.Error("creating a task with a used ID succeeded")
+			}
+			kept, err := store.Create(Task{UID: "abc", Description: "synced"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept[0].ID != 9 || kept[0].UID != "abc" {
+				t.Errorf("synced task = %d %q, want 9 \"abc\"", kept[0].ID, kept[0].UID)
+			}
+
+			// IDs of deleted tasks are not handed out again while a higher
+			// one is still in use.
+			if err := store.Delete(1); err != nil {
+				t.Fatal(err)
+			}
+			next, err := store.Create(Task{Description: "next"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next[0].ID != 10 {
+				t.Errorf("next task got ID %d, want 10", next[0].ID)
+			}
+
+			if _, err := store.Get(1); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get of a deleted task = %v, want ErrNotFound", err)
+			}
+			if err := store.Update(Task{ID: 42}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Update of a missing task = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestStoreModify(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Create(Task{Description: "keep"}, Task{Description: "change"}, Task{Description: "drop"}); err != nil {
+				t.Fatal(err)
+			}
+
+			refused := errors.New("refused")
+			err := store.Modify(func(list *[]Task) error {
+				*list = (*list)[:0]
+				return refused
+			})
+			if err != refused {
+				t.Errorf("Modify returned %v, want the error of fn", err)
+			}
+
+			err = store.Modify(func(list *[]Task) error {
+				(*list)[1].Description = "changed"
+				*list = append((*list)[:2], Task{ID: NextID(*list), Description: "added"})
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			list, err := store.List(Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, task := range list {
+				got = append(got, fmt.Sprintf("%d %s", task.ID, task.Description))
+			}
+			if want := "1 keep, 2 changed, 4 added"; strings.Join(got, ", ") != want {
+				t.Errorf("tasks after Modify = %s, want %s", strings.Join(got, ", "), want)
+			}
+
+			err = store.Modify(func(list *[]Task) error {
+				*list = append(*list, Task{ID: 1, Description: "duplicate"})
+				return nil
+			})
+			if err == nil {
+				t.Error("Modify saved two tasks with the same ID")
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Package tasks holds the task model of task-cli, the stores that keep
+// tasks and the operations that change them. It prints nothing: failures
+// come back as errors, typed where callers may want to tell them apart.
+package tasks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DateLayout is how due dates are written.
+const DateLayout = "2006-01-02"
+
+type StatusTask string
+
+const (
+	StatusTaskTodo       StatusTask = "todo"
+	StatusTaskInProgress StatusTask = "in-progress"
+	StatusTaskDone       StatusTask = "done"
+)
+
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+)
+
+// Rank orders priorities from highest to lowest; tasks without a priority sort last.
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityHigh:
+		return 3
+	case PriorityMedium:
+		return 2
+	case PriorityLow:
+		return 1
+	}
+	return 0
+}
+
+func ParsePriority(value string) (Priority, error) {
+	switch Priority(value) {
+	case PriorityLow, PriorityMedium, PriorityHigh:
+		return Priority(value), nil
+	case "none":
+		return "", nil
+	}
+	return "", fmt.Errorf("invalid priority %q (use low, medium, high or none)", value)
+}
+
+type Task struct {
+	ID          int              `json:"id"`
+	UID         string           `json:"uid,omitempty"` // same on every synced copy of the list, unlike ID
+	Description string           `json:"description"`
+	Status      StatusTask       `json:"status"`
+	Priority    Priority         `json:"priority,omitempty"`
+	DueDate     *time.Time       `json:"dueDate,omitempty"`
+	Project     string           `json:"project,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
+	ParentID    int              `json:"parentId,omitempty"`
+	BlockedBy   []int            `json:"blockedBy,omitempty"`
+	Recurrence  *Recurrence      `json:"recurrence,omitempty"`
+	NextID      int              `json:"nextId,omitempty"` // occurrence created when a recurring task was done
+	TimeLog     []WorkInterval   `json:"timeLog,omitempty"`
+	Notes       []Note           `json:"notes,omitempty"`
+	StatusLog   []StatusLogEntry `json:"statusLog,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+// NewUID returns a random identifier for a task. Unlike the numeric ID,
+// which is only unique within one copy of a list, it is the same on every
+// machine the list is synced to.
+func NewUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (t Task) HasTag(tag string) bool {
+	for _, existing := range t.Tags {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Task) AddTags(tags []string) {
+	for _, tag := range tags {
+		if !t.HasTag(tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+}
+
+func (t *Task) RemoveTags(tags []string) {
+	var kept []string
+	for _, existing := range t.Tags {
+		remove := false
+		for _, tag := range tags {
+			if strings.EqualFold(existing, tag) {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			kept = append(kept, existing)
+		}
+	}
+	t.Tags = kept
+}
+
+func (t *Task) AddBlockers(ids []int) {
+	for _, id := range ids {
+		if !slices.Contains(t.BlockedBy, id) {
+			t.BlockedBy = append(t.BlockedBy, id)
+		}
+	}
+}
+
+func (t *Task) RemoveBlockers(ids []int) {
+	t.BlockedBy = slices.DeleteFunc(t.BlockedBy, func(id int) bool {
+		return slices.Contains(ids, id)
+	})
+	if len(t.BlockedBy) == 0 {
+		t.BlockedBy = nil
+	}
+}
+
+func (t Task) IsOverdue() bool {
+	return t.DueDate != nil && t.DueDate.Before(StartOfDay(time.Now()))
+}
+
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Update holds optional changes to a task. Nil fields are left untouched.
+type Update struct {
+	Description *string
+	Priority    *Priority
+	DueDate     *time.Time
+	ClearDue    bool
+	Project     *string
+	AddTags     []string
+	RemoveTags  []string
+	ParentID    *int
+	AddBlockers []int
+	Unblock     []int
+	Recurrence  *Recurrence
+	NoRepeat    bool
+}
+
+func (u Update) Empty() bool {
+	return u.Description == nil && u.Priority == nil && u.DueDate == nil && !u.ClearDue &&
+		u.Project == nil && len(u.AddTags) == 0 && len(u.RemoveTags) == 0 &&
+		u.ParentID == nil && len(u.AddBlockers) == 0 && len(u.Unblock) == 0 &&
+		u.Recurrence == nil && !u.NoRepeat
+}
+
+func (u Update) Apply(task *Task) {
+	if u.Description != nil {
+		task.Description = *u.Description
+	}
+	if u.Priority != nil {
+		task.Priority = *u.Priority
+	}
+	if u.DueDate != nil {
+		task.DueDate = u.DueDate
+	}
+	if u.ClearDue {
+		task.DueDate = nil
+	}
+	if u.Project != nil {
+		task.Project = *u.Project
+	}
+	task.AddTags(u.AddTags)
+	task.RemoveTags(u.RemoveTags)
+	if u.ParentID != nil {
+		task.ParentID = *u.ParentID
+	}
+	task.AddBlockers(u.AddBlockers)
+	task.RemoveBlockers(u.Unblock)
+	if u.Recurrence != nil {
+		task.Recurrence = u.Recurrence
+	}
+	if u.NoRepeat {
+		task.Recurrence = nil
+	}
+}
+
+// Note is a free-form, possibly multi-line, comment attached to a task.
+type Note struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StatusLogEntry records one status change of a task.
+type StatusLogEntry struct {
+	From StatusTask `json:"from"`
+	To   StatusTask `json:"to"`
+	At   time.Time  `json:"at"`
+}
+
+// SetStatus changes the status of the task and logs the change. Callers
+// still set UpdatedAt.
+func (t *Task) SetStatus(status StatusTask, now time.Time) {
+	if t.Status == status {
+		return
+	}
+	t.StatusLog = append(t.StatusLog, StatusLogEntry{From: t.Status, To: status, At: now})
+	t.Status = status
+}
+
+// WorkInterval is one stretch of tracked work on a task. End is nil while
+// the timer is running.
+type WorkInterval struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+func (t Task) TimerRunning() bool {
+	n := len(t.TimeLog)
+	return n > 0 && t.TimeLog[n-1].End == nil
+}
+
+// StopTimer closes the running interval, if any, and returns its length.
+func (t *Task) StopTimer(now time.Time) time.Duration {
+	if !t.TimerRunning() {
+		return 0
+	}
+	last := &t.TimeLog[len(t.TimeLog)-1]
+	last.End = &now
+	return now.Sub(last.Start)
+}
+
+// RunningTask returns the task whose timer is running, if there is one.
+func RunningTask(tasks []Task) (Task, bool) {
+	for _, task := range tasks {
+		if task.TimerRunning() {
+			return task, true
+		}
+	}
+	return Task{}, false
+}
+
+// TrackedTime sums the work logged on the task between from and to,
+// counting a running timer up to now.
+func (t Task) TrackedTime(from, to, now time.Time) time.Duration {
+	var total time.Duration
+	for _, interval := range t.TimeLog {
+		start, end := interval.Clip(from, to, now)
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+// Clip returns the part of the interval between from and to, counting a
+// running timer up to now. A zero from leaves the start as it is.
+func (w WorkInterval) Clip(from, to, now time.Time) (time.Time, time.Time) {
+	start, end := w.Start, now
+	if w.End != nil {
+		end = *w.End
+	}
+	if !from.IsZero() && start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	return start, end
+}
@@ -0,0 +1,552 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Tracker makes the changes to one task list that have to keep it
+// consistent: links are checked, the workflow is followed and recurring
+// tasks are rescheduled when they are finished. Every operation reads,
+// checks and writes inside a single Store.Modify, so what it checked still
+// holds when its change is saved.
+type Tracker struct {
+	Store    Store
+	Workflow Workflow
+	// Check, when set, is called with the task that Edit, SetStatus or
+	// Remove is about to change, as it is inside the transaction. An error
+	// refuses the change and is returned as it is.
+	Check func(task Task) error
+}
+
+// txn is the task list inside one store transaction, with an index that
+// follows the changes made through it.
+type txn struct {
+	tasks *[]Task
+	index Index
+}
+
+func (tx txn) get(id int) (Task, error) {
+	task, ok := tx.index[id]
+	if !ok {
+		return Task{}, &NotFoundError{ID: id}
+	}
+	return task, nil
+}
+
+// put replaces the stored task with the same ID.
+func (tx txn) put(task Task) {
+	(*tx.tasks)[indexOfTask(*tx.tasks, task.ID)] = task
+	tx.index[task.ID] = task
+}
+
+// add stores a new task under the next free ID.
+func (tx txn) add(task Task) Task {
+	if task.UID == "" {
+		task.UID = NewUID()
+	}
+	task.ID = NextID(*tx.tasks)
+	*tx.tasks = append(*tx.tasks, task)
+	tx.index[task.ID] = task
+	return task
+}
+
+func (tx txn) remove(ids []int) {
+	drop := map[int]bool{}
+	for _, id := range ids {
+		drop[id] = true
+		delete(tx.index, id)
+	}
+	kept := (*tx.tasks)[:0]
+	for _, task := range *tx.tasks {
+		if !drop[task.ID] {
+			kept = append(kept, task)
+		}
+	}
+	*tx.tasks = kept
+}
+
+// target reads the task an operation changes and lets Check refuse it.
+func (tr Tracker) target(tx txn, id int) (Task, error) {
+	task, err := tx.get(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if tr.Check != nil {
+		if err := tr.Check(task); err != nil {
+			return Task{}, err
+		}
+	}
+	return task, nil
+}
+
+// modify runs fn in one store transaction. Errors from fn, which refuse
+// the change, are returned as they are; failing to load or save is a
+// *StoreError.
+func (tr Tracker) modify(fn func(tx txn) error) error {
+	var refused error
+	err := tr.Store.Modify(func(tasks *[]Task) error {
+		refused = fn(txn{tasks: tasks, index: NewIndex(*tasks)})
+		return refused
+	})
+	if err != nil && err == refused {
+		return err
+	}
+	return storeError(err)
+}
+
+// Add checks the links of a new task and stores it in the initial status
+// of the workflow.
+func (tr Tracker) Add(task Task) (Task, error) {
+	err := tr.modify(func(tx txn) error {
+		if err := tx.index.ValidateLinks(task); err != nil {
+			return err
+		}
+		task.Status = tr.Workflow.Initial
+		task.CreatedAt = time.Now()
+		task.UpdatedAt = task.CreatedAt
+		task = tx.add(task)
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// Edit applies update to task id after checking its new links.
+func (tr Tracker) Edit(id int, update Update) (Task, error) {
+	return tr.EditWith(id, func(Task) (Update, error) { return update, nil })
+}
+
+// EditWith edits task id as Edit does, with the update fn makes from the
+// task as it is inside the transaction. An error from fn is returned as
+// it is.
+func (tr Tracker) EditWith(id int, fn func(current Task) (Update, error)) (Task, error) {
+	var task Task
+	err := tr.modify(func(tx txn) error {
+		var err error
+		if task, err = tr.target(tx, id); err != nil {
+			return err
+		}
+		update, err := fn(task)
+		if err != nil {
+			return err
+		}
+		update.Apply(&task)
+		if err := tx.index.ValidateLinks(task); err != nil {
+			return err
+		}
+		task.UpdatedAt = time.Now()
+		tx.put(task)
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// StatusChange is the outcome of SetStatus.
+type StatusChange struct {
+	Task Task
+	// FollowUps are the next occurrences of a completed recurring task.
+	FollowUps []Task
+	// Stopped is how long the timer had been running, if completing the
+	// task stopped it.
+	Stopped time.Duration
+	// OpenBlockers were overridden with force.
+	OpenBlockers []int
+	OpenSubtasks int
+}
+
+// SetStatus moves task id to status along a transition the workflow
+// allows. Moving a task to a terminal status is refused with a
+// *BlockedError while it has open blockers, unless forced; it also stops
+// its timer and schedules the next occurrence of a recurring task.
+func (tr Tracker) SetStatus(id int, status StatusTask, force bool) (StatusChange, error) {
+	var change StatusChange
+	err := tr.modify(func(tx txn) error {
+		task, err := tr.target(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tr.Workflow.CheckTransition(task, status); err != nil {
+			return err
+		}
+
+		terminal := tr.Workflow.IsTerminal(status)
+		if terminal {
+			if open := tx.index.OpenBlockers(task, tr.Workflow); len(open) > 0 {
+				if !force {
+					return &BlockedError{ID: id, Status: status, Blockers: open}
+				}
+				change.OpenBlockers = open
+			}
+			for _, child := range tx.index.Descendants(task.ID) {
+				if !tr.Workflow.IsTerminal(tx.index[child].Status) {
+					change.OpenSubtasks++
+				}
+			}
+		}
+
+		task.UpdatedAt = time.Now()
+		task.SetStatus(status, task.UpdatedAt)
+		if terminal {
+			change.Stopped = task.StopTimer(task.UpdatedAt)
+			finished := []Task{task}
+			change.FollowUps = tr.complete(tx, finished)
+			task = finished[0]
+		} else {
+			tx.put(task)
+		}
+		change.Task = task
+		return nil
+	})
+	if err != nil {
+		return StatusChange{}, err
+	}
+	return change, nil
+}
+
+// BulkStatusChange is the outcome of SetStatusMatching.
+type BulkStatusChange struct {
+	Changed   []Task
+	FollowUps []Task
+	// Skipped holds a *TransitionError or *BlockedError for each matching
+	// task that was left as it was.
+	Skipped []error
+}
+
+// SetStatusMatching moves every task matching filter to status, skipping
+// those the workflow or open blockers keep where they are.
+func (tr Tracker) SetStatusMatching(filter Filter, status StatusTask, force bool) (BulkStatusChange, error) {
+	if err := tr.Workflow.CheckStatus(status); err != nil {
+		return BulkStatusChange{}, err
+	}
+
+	var result BulkStatusChange
+	err := tr.modify(func(tx txn) error {
+		terminal := tr.Workflow.IsTerminal(status)
+		for _, task := range *tx.tasks {
+			if !filter.Match(task) || task.Status == status {
+				continue
+			}
+			if err := tr.Workflow.CheckTransition(task, status); err != nil {
+				result.Skipped = append(result.Skipped, err)
+				continue
+			}
+			if terminal {
+				if open := tx.index.OpenBlockers(task, tr.Workflow); len(open) > 0 && !force {
+					result.Skipped = append(result.Skipped, &BlockedError{ID: task.ID, Status: status, Blockers: open})
+					continue
+				}
+			}
+			task.UpdatedAt = time.Now()
+			task.SetStatus(status, task.UpdatedAt)
+			if terminal {
+				task.StopTimer(task.UpdatedAt)
+			}
+			result.Changed = append(result.Changed, task)
+		}
+
+		if terminal {
+			result.FollowUps = tr.complete(tx, result.Changed)
+			return nil
+		}
+		for _, task := range result.Changed {
+			tx.put(task)
+		}
+		return nil
+	})
+	if err != nil {
+		return BulkStatusChange{}, err
+	}
+	return result, nil
+}
+
+// complete stores tasks that have just been finished and creates the next
+// occurrence of the recurring ones, which it returns. Each completed task
+// remembers its follow-up so that re-completing it does not repeat it.
+func (tr Tracker) complete(tx txn, tasks []Task) []Task {
+	now := time.Now()
+	var created []Task
+	for i, task := range tasks {
+		if next, ok := nextOccurrence(task, tr.Workflow.Initial, now); ok {
+			next = tx.add(next)
+			tasks[i].NextID = next.ID
+			created = append(created, next)
+		}
+		tx.put(tasks[i])
+	}
+	return created
+}
+
+// Remove deletes task id, and its subtasks when cascade is set, and
+// returns the deleted tasks.
+func (tr Tracker) Remove(id int, cascade bool) ([]Task, error) {
+	var deleted []Task
+	err := tr.modify(func(tx txn) error {
+		if _, err := tr.target(tx, id); err != nil {
+			return err
+		}
+		var err error
+		deleted, err = removeIDs(tx, []int{id}, cascade)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RemoveMatching deletes every task matching filter, as Remove does.
+func (tr Tracker) RemoveMatching(filter Filter, cascade bool) ([]Task, error) {
+	var deleted []Task
+	err := tr.modify(func(tx txn) error {
+		var ids []int
+		for _, task := range *tx.tasks {
+			if filter.Match(task) {
+				ids = append(ids, task.ID)
+			}
+		}
+		var err error
+		deleted, err = removeIDs(tx, ids, cascade)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RemoveIDs deletes ids as Remove does, all in one transaction.
+func (tr Tracker) RemoveIDs(ids []int, cascade bool) ([]Task, error) {
+	var deleted []Task
+	err := tr.modify(func(tx txn) error {
+		for _, id := range ids {
+			if _, err := tx.get(id); err != nil {
+				return err
+			}
+		}
+		var err error
+		deleted, err = removeIDs(tx, ids, cascade)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// removeIDs deletes ids, and their subtasks when cascade is set, and drops
+// them from the blockers of the remaining tasks. It refuses to leave
+// subtasks without their parent with a *SubtasksError.
+func removeIDs(tx txn, ids []int, cascade bool) ([]Task, error) {
+	remove := map[int]bool{}
+	var order []int
+	for _, id := range ids {
+		remove[id] = true
+		order = append(order, id)
+	}
+	for _, id := range ids {
+		for _, child := range tx.index.Descendants(id) {
+			if remove[child] {
+				continue
+			}
+			if !cascade {
+				return nil, &SubtasksError{ID: id}
+			}
+			remove[child] = true
+			order = append(order, child)
+		}
+	}
+	if len(order) == 0 {
+		return nil, nil
+	}
+
+	for _, task := range *tx.tasks {
+		if remove[task.ID] {
+			continue
+		}
+		before := len(task.BlockedBy)
+		task.RemoveBlockers(order)
+		if len(task.BlockedBy) != before {
+			task.UpdatedAt = time.Now()
+			tx.put(task)
+		}
+	}
+
+	deleted := make([]Task, len(order))
+	for i, id := range order {
+		deleted[i] = tx.index[id]
+	}
+	tx.remove(order)
+	return deleted, nil
+}
+
+// StartTimer starts the work timer of task id, refusing with a
+// *TimerRunningError while any timer is running. A task still in the
+// initial status moves to in-progress when the workflow allows it.
+func (tr Tracker) StartTimer(id int) (Task, error) {
+	var task Task
+	err := tr.modify(func(tx txn) error {
+		if running, ok := RunningTask(*tx.tasks); ok {
+			return &TimerRunningError{ID: id, Running: running.ID}
+		}
+		var err error
+		if task, err = tx.get(id); err != nil {
+			return err
+		}
+		now := time.Now()
+		task.TimeLog = append(task.TimeLog, WorkInterval{Start: now})
+		if task.Status == tr.Workflow.Initial && tr.Workflow.CheckTransition(task, StatusTaskInProgress) == nil {
+			task.SetStatus(StatusTaskInProgress, now)
+		}
+		task.UpdatedAt = now
+		tx.put(task)
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// StopTimer stops the running timer and returns the task with how long the
+// timer ran. When id is non-zero it must be the task being timed, or the
+// timer is left running with a *NotTimedError. ErrNoTimer is returned when
+// no timer is running.
+func (tr Tracker) StopTimer(id int) (Task, time.Duration, error) {
+	var (
+		task    Task
+		elapsed time.Duration
+	)
+	err := tr.modify(func(tx txn) error {
+		running, ok := RunningTask(*tx.tasks)
+		if !ok {
+			return ErrNoTimer
+		}
+		if id != 0 && running.ID != id {
+			return &NotTimedError{ID: id, Running: running.ID}
+		}
+		task = running
+		task.UpdatedAt = time.Now()
+		elapsed = task.StopTimer(task.UpdatedAt)
+		tx.put(task)
+		return nil
+	})
+	if err != nil {
+		return Task{}, 0, err
+	}
+	return task, elapsed, nil
+}
+
+// Archive moves the finished tasks matching filter that were last changed
+// before cutoff out of the list and into archive, and returns them. A task
+// is only archived together with all of its subtasks, so no subtask loses
+// its parent. Archived tasks keep their IDs; a task archived again
+// replaces its earlier copy.
+func (tr Tracker) Archive(archive *JSONStore, filter Filter, cutoff time.Time) ([]Task, error) {
+	var archived []Task
+	err := tr.modify(func(tx txn) error {
+		candidates := map[int]bool{}
+		for _, task := range *tx.tasks {
+			if tr.Workflow.IsTerminal(task.Status) && task.UpdatedAt.Before(cutoff) && filter.Match(task) {
+				candidates[task.ID] = true
+			}
+		}
+		var ids []int
+		for _, task := range *tx.tasks {
+			if !candidates[task.ID] {
+				continue
+			}
+			complete := true
+			for _, child := range tx.index.Descendants(task.ID) {
+				if !candidates[child] {
+					complete = false
+					break
+				}
+			}
+			if complete {
+				ids = append(ids, task.ID)
+				archived = append(archived, task)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// The archive is written first: if saving the list fails, the
+		// tasks are in both places rather than lost.
+		err := archive.Modify(func(stored *[]Task) error {
+			for _, task := range archived {
+				if i := indexOfTask(*stored, task.ID); i >= 0 {
+					(*stored)[i] = task
+				} else {
+					*stored = append(*stored, task)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return &StoreError{Err: fmt.Errorf("writing the archive: %w", err)}
+		}
+		_, err = removeIDs(tx, ids, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// Restore moves archived task id back into the list and returns it as
+// restored. It gets a new ID when its old one has been reused meanwhile,
+// and loses the links to a parent or blockers that no longer exist. A task
+// that is in the list already, because archiving it was undone, is only
+// dropped from the archive.
+func (tr Tracker) Restore(archive *JSONStore, id int) (Task, error) {
+	var task Task
+	err := tr.modify(func(tx txn) error {
+		var err error
+		if task, err = archive.Get(id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return &NotFoundError{ID: id, Archived: true}
+			}
+			return &StoreError{Err: err}
+		}
+		for _, current := range *tx.tasks {
+			if task.UID != "" && current.UID == task.UID {
+				task = current
+				return nil
+			}
+		}
+
+		if _, taken := tx.index[task.ID]; taken {
+			task.ID = NextID(*tx.tasks)
+		}
+		if _, ok := tx.index[task.ParentID]; !ok {
+			task.ParentID = 0
+		}
+		var missing []int
+		for _, blocker := range task.BlockedBy {
+			if _, ok := tx.index[blocker]; !ok {
+				missing = append(missing, blocker)
+			}
+		}
+		task.RemoveBlockers(missing)
+		*tx.tasks = append(*tx.tasks, task)
+		tx.index[task.ID] = task
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	// The archived copy is dropped only once the task is back in the list.
+	if err := archive.Delete(id); err != nil && !errors.Is(err, ErrNotFound) {
+		return task, &StoreError{Err: fmt.Errorf("removing the task from the archive: %w", err)}
+	}
+	return task, nil
+}
@@ -0,0 +1,226 @@
+package tasks
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrackerErrors(t *testing.T) {
+	w := DefaultWorkflow
+	w.Transitions = map[StatusTask][]StatusTask{
+		StatusTaskTodo:       {StatusTaskInProgress},
+		StatusTaskInProgress: {StatusTaskDone, StatusTaskTodo},
+	}
+	tr := Tracker{Store: NewJSONStore(filepath.Join(t.TempDir(), "tasks.json")), Workflow: w}
+	add := func(task Task) Task {
+		t.Helper()
+		task, err := tr.Add(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	parent := add(Task{Description: "parent"})
+	blocker := add(Task{Description: "blocker"})
+	child := add(Task{Description: "child", ParentID: parent.ID, BlockedBy: []int{blocker.ID}})
+	if child.Status != StatusTaskTodo || child.UID == "" {
+		t.Errorf("added task = %+v, want a todo task with a UID", child)
+	}
+
+	var notFound *NotFoundError
+	if _, err := tr.Edit(99, Update{}); !errors.As(err, &notFound) || notFound.ID != 99 || !errors.Is(err, ErrNotFound) {
+		t.Errorf("Edit of a missing task = %v, want a NotFoundError", err)
+	}
+	var link *LinkError
+	self := child.ID
+	if _, err := tr.Edit(parent.ID, Update{ParentID: &self}); !errors.As(err, &link) {
+		t.Errorf("Edit making a cycle = %v, want a LinkError", err)
+	}
+	if _, err := tr.Add(Task{Description: "orphan", BlockedBy: []int{42}}); !errors.As(err, &link) {
+		t.Errorf("Add with a missing blocker = %v, want a LinkError", err)
+	}
+
+	var invalid *InvalidStatusError
+	if _, err := tr.SetStatus(child.ID, "finished", false); !errors.As(err, &invalid) || invalid.Status != "finished" {
+		t.Errorf("SetStatus to an unknown status = %v, want an InvalidStatusError", err)
+	}
+	var transition *TransitionError
+	if _, err := tr.SetStatus(child.ID, StatusTaskDone, false); !errors.As(err, &transition) || transition.From != StatusTaskTodo {
+		t.Errorf("SetStatus skipping in-progress = %v, want a TransitionError", err)
+	}
+
+	if _, err := tr.SetStatus(child.ID, StatusTaskInProgress, false); err != nil {
+		t.Fatal(err)
+	}
+	var blocked *BlockedError
+	if _, err := tr.SetStatus(child.ID, StatusTaskDone, false); !errors.As(err, &blocked) || len(blocked.Blockers) != 1 || blocked.Blockers[0] != blocker.ID {
+		t.Errorf("SetStatus of a blocked task = %v, want a BlockedError", err)
+	}
+	change, err := tr.SetStatus(child.ID, StatusTaskDone, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Task.Status != StatusTaskDone || len(change.OpenBlockers) != 1 {
+		t.Errorf("forced change = %+v, want done with the blocker reported", change)
+	}
+
+	var subtasks *SubtasksError
+	if _, err := tr.Remove(parent.ID, false); !errors.As(err, &subtasks) || subtasks.ID != parent.ID {
+		t.Errorf("Remove of a parent = %v, want a SubtasksError", err)
+	}
+	deleted, err := tr.Remove(parent.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("cascading Remove deleted %d task(s), want 2", len(deleted))
+	}
+}
+
+func TestTrackerCheck(t *testing.T) {
+	tr := Tracker{Store: NewJSONStore(filepath.Join(t.TempDir(), "tasks.json")), Workflow: DefaultWorkflow}
+	task, err := tr.Add(Task{Description: "checked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := errors.New("stale")
+	tr.Check = func(current Task) error {
+		if !current.UpdatedAt.Equal(task.UpdatedAt) {
+			return stale
+		}
+		return nil
+	}
+	renamed := "renamed"
+	edited, err := tr.EditWith(task.ID, func(current Task) (Update, error) {
+		if current.Description != "checked" {
+			t.Errorf("EditWith got %+v, want the stored task", current)
+		}
+		return Update{Description: &renamed}, nil
+	})
+	if err != nil || edited.Description != renamed {
+		t.Fatalf("EditWith = %+v, %v", edited, err)
+	}
+
+	// task is now out of date, so every change to it is refused.
+	if _, err := tr.Edit(task.ID, Update{}); err != stale {
+		t.Errorf("Edit refused by Check = %v, want its error", err)
+	}
+	if _, err := tr.SetStatus(task.ID, StatusTaskDone, false); err != stale {
+		t.Errorf("SetStatus refused by Check = %v, want its error", err)
+	}
+	if _, err := tr.Remove(task.ID, false); err != stale {
+		t.Errorf("Remove refused by Check = %v, want its error", err)
+	}
+	if current, err := tr.Store.Get(task.ID); err != nil || current.Description != renamed || current.Status != StatusTaskTodo {
+		t.Errorf("task after refused changes = %+v, %v", current, err)
+	}
+}
+
+func TestTrackerSchedulesNextOccurrence(t *testing.T) {
+	tr := Tracker{Store: NewJSONStore(filepath.Join(t.TempDir(), "tasks.json")), Workflow: DefaultWorkflow}
+	due := StartOfDay(time.Now()).AddDate(0, 0, -1)
+	task, err := tr.Add(Task{Description: "water plants", DueDate: &due, Recurrence: &Recurrence{Kind: RecurInterval, Days: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter, _ := ParseFilter([]string{"water"}, tr.Workflow)
+	result, err := tr.SetStatusMatching(filter, StatusTaskDone, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changed) != 1 || len(result.FollowUps) != 1 {
+		t.Fatalf("SetStatusMatching = %+v, want one change and one follow-up", result)
+	}
+	next := result.FollowUps[0]
+	if want := due.AddDate(0, 0, 3); !next.DueDate.Equal(want) || next.Status != StatusTaskTodo {
+		t.Errorf("next occurrence due %s in %s, want %s in todo", next.DueDate.Format(DateLayout), next.Status, want.Format(DateLayout))
+	}
+	stored, err := tr.Store.Get(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.NextID != next.ID {
+		t.Errorf("completed task points at %d, want %d", stored.NextID, next.ID)
+	}
+
+	// Completing the task again does not schedule a second follow-up.
+	if _, err := tr.SetStatus(task.ID, StatusTaskTodo, false); err != nil {
+		t.Fatal(err)
+	}
+	change, err := tr.SetStatus(task.ID, StatusTaskDone, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(change.FollowUps) != 0 {
+		t.Errorf("re-completing scheduled %d follow-up(s)", len(change.FollowUps))
+	}
+}
+
+func TestTrackerTimer(t *testing.T) {
+	tr := Tracker{Store: NewJSONStore(filepath.Join(t.TempDir(), "tasks.json")), Workflow: DefaultWorkflow}
+	first, _ := tr.Add(Task{Description: "first"})
+	second, _ := tr.Add(Task{Description: "second"})
+
+	if _, _, err := tr.StopTimer(0); !errors.Is(err, ErrNoTimer) {
+		t.Errorf("StopTimer with no timer = %v, want ErrNoTimer", err)
+	}
+	started, err := tr.StartTimer(first.ID)
+	if err != nil || !started.TimerRunning() || started.Status != StatusTaskInProgress {
+		t.Fatalf("StartTimer = %+v, %v; want a running timer in progress", started, err)
+	}
+	var running *TimerRunningError
+	if _, err := tr.StartTimer(second.ID); !errors.As(err, &running) || running.Running != first.ID {
+		t.Errorf("StartTimer while another runs = %v, want a TimerRunningError", err)
+	}
+	var notTimed *NotTimedError
+	if _, _, err := tr.StopTimer(second.ID); !errors.As(err, &notTimed) || notTimed.Running != first.ID {
+		t.Errorf("StopTimer of the wrong task = %v, want a NotTimedError", err)
+	}
+	stopped, _, err := tr.StopTimer(first.ID)
+	if err != nil || stopped.TimerRunning() {
+		t.Errorf("StopTimer = %+v, %v", stopped, err)
+	}
+}
+
+func TestTrackerArchiveAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	tr := Tracker{Store: NewJSONStore(filepath.Join(dir, "tasks.json")), Workflow: DefaultWorkflow}
+	archive := NewJSONStore(filepath.Join(dir, "archive.json"))
+	parent, _ := tr.Add(Task{Description: "parent"})
+	child, _ := tr.Add(Task{Description: "child", ParentID: parent.ID})
+	tr.Add(Task{Description: "open", ParentID: parent.ID})
+	tr.SetStatus(parent.ID, StatusTaskDone, true)
+	tr.SetStatus(child.ID, StatusTaskDone, false)
+	later := time.Now().Add(time.Minute)
+
+	// The parent stays while one of its subtasks is open.
+	archived, err := tr.Archive(archive, Filter{}, later)
+	if err != nil || len(archived) != 1 || archived[0].ID != child.ID {
+		t.Fatalf("Archive = %+v, %v; want only the finished subtask", archived, err)
+	}
+	if _, err := tr.Store.Get(child.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("archived task still in the list: %v", err)
+	}
+
+	// Task 2 gets reused while the parent goes away.
+	tr.Remove(parent.ID, true)
+	tr.Add(Task{Description: "gone"})
+	tr.Add(Task{Description: "takes the ID"})
+	tr.Remove(1, false)
+	restored, err := tr.Restore(archive, child.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID != 3 || restored.ParentID != 0 || restored.Description != "child" {
+		t.Errorf("restored task = %+v, want task 3 without a parent", restored)
+	}
+
+	var notFound *NotFoundError
+	if _, err := tr.Restore(archive, child.ID); !errors.As(err, &notFound) || !notFound.Archived {
+		t.Errorf("restoring it again = %v, want a NotFoundError for the archive", err)
+	}
+}
@@ -0,0 +1,174 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Workflow defines the statuses a task can have and how it may move between
+// them. A list can bring its own in a file next to the task file, such as
+// .tasks.workflow.json:
+//
+//	{
+//	  "statuses": ["todo", "in-progress", "review", "blocked", "done"],
+//	  "terminal": ["done"],
+//	  "transitions": {
+//	    "todo": ["in-progress", "blocked"],
+//	    "in-progress": ["review", "blocked", "todo"],
+//	    "review": ["in-progress", "done"],
+//	    "blocked": ["todo", "in-progress"],
+//	    "done": ["todo"]
+//	  }
+//	}
+//
+// New tasks start in initial, by default the first status. Reaching a
+// terminal status completes the task: its timer stops, it no longer blocks
+// other tasks and a recurring task schedules its next occurrence. Without
+// transitions any move is allowed; with them, a status that has no entry
+// cannot be left.
+type Workflow struct {
+	Statuses    []StatusTask                `json:"statuses"`
+	Initial     StatusTask                  `json:"initial,omitempty"`
+	Terminal    []StatusTask                `json:"terminal"`
+	Transitions map[StatusTask][]StatusTask `json:"transitions,omitempty"`
+}
+
+// DefaultWorkflow is the fixed todo, in-progress, done cycle used when no
+// workflow file exists.
+var DefaultWorkflow = Workflow{
+	Statuses: []StatusTask{StatusTaskTodo, StatusTaskInProgress, StatusTaskDone},
+	Initial:  StatusTaskTodo,
+	Terminal: []StatusTask{StatusTaskDone},
+}
+
+// LoadWorkflow reads a workflow file, or returns the default workflow when
+// there is none.
+func LoadWorkflow(path string) (Workflow, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultWorkflow, nil
+	}
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	var w Workflow
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&w); err != nil {
+		return Workflow{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if w.Initial == "" && len(w.Statuses) > 0 {
+		w.Initial = w.Statuses[0]
+	}
+	if err := w.Validate(); err != nil {
+		return Workflow{}, fmt.Errorf("invalid workflow in %s: %w", path, err)
+	}
+	return w, nil
+}
+
+func (w Workflow) Validate() error {
+	if len(w.Statuses) == 0 {
+		return errors.New("no statuses defined")
+	}
+	seen := map[StatusTask]bool{}
+	for _, status := range w.Statuses {
+		if status == "" || strings.ContainsAny(string(status), " \t:,") || strings.HasPrefix(string(status), "-") {
+			return fmt.Errorf("invalid status name %q", status)
+		}
+		if IsFilterField(string(status)) {
+			return fmt.Errorf("status %q clashes with the filter field of the same name", status)
+		}
+		if seen[status] {
+			return fmt.Errorf("status %q listed twice", status)
+		}
+		seen[status] = true
+	}
+	if !seen[w.Initial] {
+		return fmt.Errorf("unknown initial status %q", w.Initial)
+	}
+	if len(w.Terminal) == 0 {
+		return errors.New("at least one terminal status is needed")
+	}
+	for _, status := range w.Terminal {
+		if !seen[status] {
+			return fmt.Errorf("unknown terminal status %q", status)
+		}
+	}
+	if w.IsTerminal(w.Initial) {
+		return fmt.Errorf("initial status %q cannot be terminal", w.Initial)
+	}
+	for from, targets := range w.Transitions {
+		if !seen[from] {
+			return fmt.Errorf("transition from unknown status %q", from)
+		}
+		for _, to := range targets {
+			if !seen[to] {
+				return fmt.Errorf("transition from %s to unknown status %q", from, to)
+			}
+		}
+	}
+	return nil
+}
+
+func (w Workflow) Known(status StatusTask) bool {
+	return slices.Contains(w.Statuses, status)
+}
+
+func (w Workflow) IsTerminal(status StatusTask) bool {
+	return slices.Contains(w.Terminal, status)
+}
+
+// DoneStatus is the status mark-done moves to and an imported checked box
+// means: done when the workflow has it as a terminal status, otherwise the
+// first terminal status.
+func (w Workflow) DoneStatus() StatusTask {
+	if w.IsTerminal(StatusTaskDone) {
+		return StatusTaskDone
+	}
+	return w.Terminal[0]
+}
+
+// Allows reports whether a task may move from one status to another.
+// Tasks left in a status the workflow no longer knows may go anywhere.
+func (w Workflow) Allows(from, to StatusTask) bool {
+	if w.Transitions == nil || from == to || !w.Known(from) {
+		return true
+	}
+	return slices.Contains(w.Transitions[from], to)
+}
+
+// CheckStatus refuses statuses the workflow does not define with an
+// *InvalidStatusError.
+func (w Workflow) CheckStatus(status StatusTask) error {
+	if !w.Known(status) {
+		return &InvalidStatusError{Status: status, Valid: w.Statuses}
+	}
+	return nil
+}
+
+// CheckTransition refuses moving a task to an unknown status or along a
+// transition the workflow does not allow, with an *InvalidStatusError or a
+// *TransitionError.
+func (w Workflow) CheckTransition(task Task, to StatusTask) error {
+	if err := w.CheckStatus(to); err != nil {
+		return err
+	}
+	if !w.Allows(task.Status, to) {
+		return &TransitionError{ID: task.ID, From: task.Status, To: to, Allowed: w.Transitions[task.Status]}
+	}
+	return nil
+}
+
+func JoinStatuses(statuses []StatusTask) string {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+	return strings.Join(names, ", ")
+}
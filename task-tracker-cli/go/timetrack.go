@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"task-cli/tasks"
+)
+
+func startTimer(store tasks.Store, id int) error {
+	task, err := tracker(store).StartTimer(id)
+	if err != nil {
+		return err
+	}
+	out.info("Timer started for task %d", id)
+	out.changed([]tasks.Task{task})
+	return nil
+}
+
+// stopTimerCommand stops the running timer. When id is non-zero it must be
+// the task being timed.
+func stopTimerCommand(store tasks.Store, id int) error {
+	task, elapsed, err := tracker(store).StopTimer(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	out.info("Timer stopped for task %d after %s (total %s)", task.ID, formatDuration(elapsed), formatDuration(task.TrackedTime(time.Time{}, now, now)))
+	out.changed([]tasks.Task{task})
+	return nil
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// reportRow is one line of a time report.
+type reportRow struct {
+	Key      string        `json:"key"`
+	Duration time.Duration `json:"-"`
+	Seconds  int64         `json:"seconds"`
+	Text     string        `json:"duration"`
+}
+
+// buildReport totals tracked time between from and to (exclusive), grouped
+// per day or per tag, and returns the overall total. Intervals crossing
+// midnight are split between days and a task with several tags counts
+// towards each of them, but only once towards the total.
+func buildReport(list []tasks.Task, from, to time.Time, by string, now time.Time) ([]reportRow, time.Duration) {
+	totals := map[string]time.Duration{}
+	var total time.Duration
+	for _, task := range list {
+		for _, interval := range task.TimeLog {
+			start, end := interval.Clip(from, to, now)
+			if !end.After(start) {
+				continue
+			}
+			total += end.Sub(start)
+			switch by {
+			case "tag":
+				tags := task.Tags
+				if len(tags) == 0 {
+					tags = []string{"(untagged)"}
+				}
+				for _, tag := range tags {
+					totals[tag] += end.Sub(start)
+				}
+			default:
+				for day := tasks.StartOfDay(start); day.Before(end); day = day.AddDate(0, 0, 1) {
+					dayStart, dayEnd := day, day.AddDate(0, 0, 1)
+					if dayStart.Before(start) {
+						dayStart = start
+					}
+					if dayEnd.After(end) {
+						dayEnd = end
+					}
+					totals[day.Format(tasks.DateLayout)] += dayEnd.Sub(dayStart)
+				}
+			}
+		}
+	}
+
+	rows := make([]reportRow, 0, len(totals))
+	for key, d := range totals {
+		rows = append(rows, reportRow{Key: key, Duration: d, Seconds: int64(d.Seconds()), Text: formatDuration(d)})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if by == "tag" && rows[i].Duration != rows[j].Duration {
+			return rows[i].Duration > rows[j].Duration
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows, total
+}
+
+func showReport(store tasks.Store, from, to time.Time, by string) error {
+	list, err := store.List(tasks.Filter{})
+	if err != nil {
+		return storageError(err)
+	}
+
+	// to is inclusive on the command line, so the report runs until the
+	// start of the following day.
+	end := to.AddDate(0, 0, 1)
+	rows, total := buildReport(list, from, end, by, time.Now())
+
+	switch out.format {
+	case outputJSON:
+		printJSON(struct {
+			From    string      `json:"from"`
+			To      string      `json:"to"`
+			By      string      `json:"by"`
+			Rows    []reportRow `json:"rows"`
+			Seconds int64       `json:"totalSeconds"`
+		}{from.Format(tasks.DateLayout), to.Format(tasks.DateLayout), by, rows, int64(total.Seconds())})
+
+	case outputCSV:
+		records := make([][]string, len(rows))
+		for i, row := range rows {
+			records[i] = []string{row.Key, fmt.Sprint(row.Seconds), row.Text}
+		}
+		printCSV([]string{by, "seconds", "duration"}, records)
+
+	default:
+		fmt.Printf("Tracked time %s to %s by %s\n\n", from.Format(tasks.DateLayout), to.Format(tasks.DateLayout), by)
+		if len(rows) == 0 {
+			fmt.Println("No time tracked in this period.")
+			return nil
+		}
+		fmt.Printf("%-20s %s\n", strings.ToUpper(by[:1])+by[1:], "Time")
+		fmt.Println("------------------------------")
+		for _, row := range rows {
+			fmt.Printf("%-20s %s\n", row.Key, row.Text)
+		}
+		fmt.Println("------------------------------")
+		fmt.Printf("%-20s %s\n", "Total", formatDuration(total))
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"task-cli/tasks"
+)
+
+type tuiMode int
+
+const (
+	tuiBrowse tuiMode = iota
+	tuiEdit
+	tuiAdd
+	tuiFilter
+)
+
+// tui is the state of the interactive board. Every change goes through the
+// same store as the other commands, so it is journaled and can be undone.
+type tui struct {
+	store *journalStore
+	// statuses are the board columns, one per status of the workflow.
+	statuses   []tasks.StatusTask
+	all        []tasks.Task
+	columns    [][]tasks.Task
+	filter     tasks.Filter
+	filterText string
+
+	col  int
+	rows []int
+
+	mode    tuiMode
+	input   []rune
+	message string
+}
+
+func runTUI(store *journalStore) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return invalidInput("tui needs an interactive terminal")
+	}
+
+	ui := &tui{store: store, statuses: workflow.Statuses, rows: make([]int, len(workflow.Statuses))}
+	if err := ui.load(); err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	w := bufio.NewWriter(os.Stdout)
+	// Switch to the alternate screen and hide the cursor; both are undone
+	// on the way out so the shell looks as it did before.
+	fmt.Fprint(w, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(w, "\x1b[?25h\x1b[?1049l")
+		w.Flush()
+	}()
+
+	for {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		ui.render(w, width, height)
+		w.Flush()
+
+		key, err := readKey(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if ui.handleKey(key) {
+			return nil
+		}
+	}
+}
+
+// readKey reads one key press and names the special keys the board uses.
+func readKey(r io.Reader) (string, error) {
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	switch key := string(buf[:n]); key {
+	case "\x1b[A", "\x1bOA":
+		return "up", nil
+	case "\x1b[B", "\x1bOB":
+		return "down", nil
+	case "\x1b[C", "\x1bOC":
+		return "right", nil
+	case "\x1b[D", "\x1bOD":
+		return "left", nil
+	case "\r", "\n":
+		return "enter", nil
+	case "\x1b":
+		return "esc", nil
+	case "\x7f", "\b":
+		return "backspace", nil
+	case "\x03":
+		return "ctrl-c", nil
+	default:
+		return key, nil
+	}
+}
+
+// load reads every task from the store and regroups the filtered ones into
+// columns, keeping the selection on the same task where possible.
+func (ui *tui) load() error {
+	selected, hasSelection := ui.selected()
+
+	all, err := ui.store.List(tasks.Filter{})
+	if err != nil {
+		return storageError(err)
+	}
+	ui.all = all
+	ui.columns = make([][]tasks.Task, len(ui.statuses))
+	for _, task := range all {
+		if !ui.filter.Match(task) {
+			continue
+		}
+		for i, status := range ui.statuses {
+			if task.Status == status {
+				ui.columns[i] = append(ui.columns[i], task)
+			}
+		}
+	}
+	for i := range ui.columns {
+		sortByPriority(ui.columns[i])
+	}
+
+	if hasSelection {
+		ui.selectTask(selected.ID)
+	}
+	ui.clampSelection()
+	return nil
+}
+
+func (ui *tui) selected() (tasks.Task, bool) {
+	if ui.col >= len(ui.columns) {
+		return tasks.Task{}, false
+	}
+	column := ui.columns[ui.col]
+	if row := ui.rows[ui.col]; row < len(column) {
+		return column[row], true
+	}
+	return tasks.Task{}, false
+}
+
+func (ui *tui) selectTask(id int) {
+	for col, column := range ui.columns {
+		for row, task := range column {
+			if task.ID == id {
+				ui.col, ui.rows[col] = col, row
+				return
+			}
+		}
+	}
+}
+
+func (ui *tui) clampSelection() {
+	for col, column := range ui.columns {
+		ui.rows[col] = max(0, min(ui.rows[col], len(column)-1))
+	}
+}
+
+// handleKey applies one key press and reports whether the board should close.
+func (ui *tui) handleKey(key string) bool {
+	if key == "ctrl-c" {
+		return true
+	}
+	if ui.mode != tuiBrowse {
+		ui.handleInput(key)
+		return false
+	}
+
+	ui.message = ""
+	ui.store.startBatch()
+	switch key {
+	case "q":
+		return true
+	case "up", "k":
+		if ui.rows[ui.col] > 0 {
+			ui.rows[ui.col]--
+		}
+	case "down", "j":
+		if ui.rows[ui.col] < len(ui.columns[ui.col])-1 {
+			ui.rows[ui.col]++
+		}
+	case "left", "h":
+		if ui.col > 0 {
+			ui.col--
+		}
+	case "right", "l":
+		if ui.col < len(ui.statuses)-1 {
+			ui.col++
+		}
+	case "<", ">":
+		task, ok := ui.selected()
+		if !ok {
+			break
+		}
+		next := ui.col + 1
+		if key == "<" {
+			next = ui.col - 1
+		}
+		if next >= 0 && next < len(ui.statuses) {
+			ui.report(ui.setStatus(task, ui.statuses[next]))
+		}
+	case "e", "enter":
+		if task, ok := ui.selected(); ok {
+			ui.mode, ui.input = tuiEdit, []rune(task.Description)
+		}
+	case "a":
+		ui.mode, ui.input = tuiAdd, nil
+	case "/":
+		ui.mode, ui.input = tuiFilter, []rune(ui.filterText)
+	case "u":
+		if batch, err := ui.store.undo(); err != nil {
+			ui.message = "Error: " + err.Error()
+		} else {
+			ui.message = fmt.Sprintf("Undone: task %d %s", batch[0].TaskID, batch[0].describe())
+		}
+		ui.report(ui.load())
+	case "r":
+		ui.report(ui.load())
+	}
+	return false
+}
+
+// handleInput edits the prompt line while editing, adding or filtering.
+func (ui *tui) handleInput(key string) {
+	switch key {
+	case "esc":
+		ui.mode, ui.input = tuiBrowse, nil
+	case "backspace":
+		if len(ui.input) > 0 {
+			ui.input = ui.input[:len(ui.input)-1]
+		}
+	case "enter":
+		mode, text := ui.mode, strings.TrimSpace(string(ui.input))
+		ui.mode, ui.input = tuiBrowse, nil
+		ui.store.startBatch()
+		switch mode {
+		case tuiEdit:
+			if task, ok := ui.selected(); ok && text != "" {
+				ui.report(ui.setDescription(task, text))
+			}
+		case tuiAdd:
+			if text != "" {
+				ui.report(ui.addTask(text))
+			}
+		case tuiFilter:
+			filter, err := tasks.ParseFilter(strings.Fields(text), workflow)
+			if err != nil {
+				ui.message = "Error: " + err.Error()
+				return
+			}
+			ui.filter, ui.filterText = filter, text
+			ui.report(ui.load())
+		}
+	default:
+		if r, _ := utf8.DecodeRuneInString(key); r >= ' ' && r != utf8.RuneError {
+			ui.input = append(ui.input, []rune(key)...)
+		}
+	}
+}
+
+func (ui *tui) report(err error) {
+	if err != nil {
+		ui.message = "Error: " + err.Error()
+	}
+}
+
+// setStatus moves a task to another column with the same rules as move:
+// the workflow must allow it, and finishing a task is refused while it has
+// open blockers, stops its timer and schedules the next occurrence of a
+// recurring task.
+func (ui *tui) setStatus(task tasks.Task, status tasks.StatusTask) error {
+	change, err := tracker(ui.store).SetStatus(task.ID, status, false)
+	if err != nil {
+		return err
+	}
+	if len(change.FollowUps) > 0 {
+		ui.message = fmt.Sprintf("Next occurrence added (ID: %d)", change.FollowUps[0].ID)
+	}
+	return ui.load()
+}
+
+func (ui *tui) setDescription(task tasks.Task, description string) error {
+	if _, err := tracker(ui.store).Edit(task.ID, tasks.Update{Description: &description}); err != nil {
+		return err
+	}
+	return ui.load()
+}
+
+func (ui *tui) addTask(description string) error {
+	task, err := tracker(ui.store).Add(tasks.Task{Description: description})
+	if err != nil {
+		return err
+	}
+	if err := ui.load(); err != nil {
+		return err
+	}
+	ui.selectTask(task.ID)
+	ui.message = fmt.Sprintf("Task added (ID: %d)", task.ID)
+	return nil
+}
+
+// render draws the whole board. Raw mode needs explicit carriage returns.
+func (ui *tui) render(w io.Writer, width, height int) {
+	fmt.Fprint(w, "\x1b[H\x1b[2J")
+
+	title := "task-cli"
+	if ui.filterText != "" {
+		title += "  filter: " + ui.filterText
+	}
+	fmt.Fprint(w, fit(title, width), "\r\n")
+
+	colWidth := max(10, (width-len(ui.statuses)+1)/len(ui.statuses))
+	headers := make([]string, len(ui.statuses))
+	for i, status := range ui.statuses {
+		headers[i] = fit(fmt.Sprintf("%s (%d)", strings.ToUpper(string(status)), len(ui.columns[i])), colWidth)
+	}
+	fmt.Fprint(w, "\x1b[1m", strings.Join(headers, "│"), "\x1b[0m\r\n")
+	fmt.Fprint(w, strings.Repeat("─", min(width, colWidth*len(ui.statuses)+len(ui.statuses)-1)), "\r\n")
+
+	// Three header lines and two footer lines surround the task rows.
+	visible := max(1, height-5)
+	offsets := make([]int, len(ui.statuses))
+	for i := range ui.statuses {
+		if ui.rows[i] >= visible {
+			offsets[i] = ui.rows[i] - visible + 1
+		}
+	}
+	index := tasks.NewIndex(ui.all)
+	for line := 0; line < visible; line++ {
+		cells := make([]string, len(ui.statuses))
+		for i, column := range ui.columns {
+			row := offsets[i] + line
+			if row >= len(column) {
+				cells[i] = strings.Repeat(" ", colWidth)
+				continue
+			}
+			cells[i] = fit(tuiLabel(column[row], index), colWidth)
+			if i == ui.col && row == ui.rows[i] {
+				cells[i] = "\x1b[7m" + cells[i] + "\x1b[0m"
+			}
+		}
+		fmt.Fprint(w, strings.Join(cells, "│"), "\r\n")
+	}
+
+	switch ui.mode {
+	case tuiEdit:
+		fmt.Fprint(w, fit("Edit: "+string(ui.input)+"_", width), "\r\n")
+	case tuiAdd:
+		fmt.Fprint(w, fit("New task: "+string(ui.input)+"_", width), "\r\n")
+	case tuiFilter:
+		fmt.Fprint(w, fit("Filter: "+string(ui.input)+"_", width), "\r\n")
+	default:
+		fmt.Fprint(w, fit(ui.message, width), "\r\n")
+	}
+	fmt.Fprint(w, "\x1b[2m", fit("arrows/hjkl move  < > status  e edit  a add  / filter  u undo  r reload  q quit", width), "\x1b[0m")
+}
+
+// tuiLabel is the one-line summary of a task on the board: its ID, a mark
+// for overdue (!), blocked (~) or timed (*) tasks, and the description.
+func tuiLabel(task tasks.Task, index tasks.Index) string {
+	mark := " "
+	switch {
+	case task.TimerRunning():
+		mark = "*"
+	case !workflow.IsTerminal(task.Status) && task.IsOverdue():
+		mark = "!"
+	case len(index.OpenBlockers(task, workflow)) > 0:
+		mark = "~"
+	}
+	return fmt.Sprintf("%3d%s %s", task.ID, mark, task.Description)
+}
+
+// fit pads or cuts s to exactly width runes.
+func fit(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
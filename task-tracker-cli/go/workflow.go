@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"task-cli/tasks"
+)
+
+// workflow is the workflow of the task list the command works on.
+var workflow = tasks.DefaultWorkflow
+
+// workflowPath is the workflow file of a task list, such as
+// .tasks.workflow.json next to tasks.json.
+func workflowPath(location storeLocation) string {
+	return siblingPath(location.path(), ".workflow.json")
+}
+
+func loadWorkflow(location storeLocation) (tasks.Workflow, error) {
+	return tasks.LoadWorkflow(workflowPath(location))
+}
+
+// showWorkflow prints the statuses of the task list and where each one
+// may lead.
+func showWorkflow(location storeLocation) error {
+	switch out.format {
+	case outputJSON:
+		printJSON(workflow)
+		return nil
+	case outputCSV:
+		// One row per status, with the statuses a task may move to next.
+		records := make([][]string, len(workflow.Statuses))
+		for i, status := range workflow.Statuses {
+			var next []string
+			for _, to := range workflow.Statuses {
+				if to != status && workflow.Allows(status, to) {
+					next = append(next, string(to))
+				}
+			}
+			records[i] = []string{string(status), fmt.Sprint(status == workflow.Initial), fmt.Sprint(workflow.IsTerminal(status)), strings.Join(next, ",")}
+		}
+		printCSV([]string{"status", "initial", "terminal", "next"}, records)
+		return nil
+	}
+	if _, err := os.Stat(workflowPath(location)); err == nil {
+		fmt.Printf("Workflow from %s\n", workflowPath(location))
+	} else {
+		fmt.Printf("Default workflow (create %s to change it)\n", workflowPath(location))
+	}
+	for _, status := range workflow.Statuses {
+		var notes []string
+		if status == workflow.Initial {
+			notes = append(notes, "initial")
+		}
+		if workflow.IsTerminal(status) {
+			notes = append(notes, "terminal")
+		}
+		targets := "any status"
+		if workflow.Transitions != nil {
+			targets = "none"
+			if next := workflow.Transitions[status]; len(next) > 0 {
+				targets = tasks.JoinStatuses(next)
+			}
+		}
+		label := string(status)
+		if len(notes) > 0 {
+			label += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Printf("  %-24s -> %s\n", label, targets)
+	}
+	return nil
+}